@@ -0,0 +1,56 @@
+package tabular
+
+import "strings"
+
+// A Border is a style of border to draw around a table.
+type Border int
+
+// These are the possible borders.
+const (
+	BorderNone    Border = iota
+	BorderASCII          // borders made of +, -, and |
+	BorderUnicode        // borders made of light box-drawing characters
+)
+
+type borderStyle struct {
+	top    ruleStyle
+	middle ruleStyle // below the header
+	bottom ruleStyle
+	v      string // vertical separator
+}
+
+// A ruleStyle describes a horizontal line drawn across a table.
+type ruleStyle struct {
+	left  string
+	fill  string
+	cross string // where a vertical separator meets the rule
+	right string
+}
+
+var borderStyles = map[Border]*borderStyle{
+	BorderASCII: {
+		top:    ruleStyle{"+", "-", "+", "+"},
+		middle: ruleStyle{"+", "-", "+", "+"},
+		bottom: ruleStyle{"+", "-", "+", "+"},
+		v:      "|",
+	},
+	BorderUnicode: {
+		top:    ruleStyle{"┌", "─", "┬", "┐"},
+		middle: ruleStyle{"├", "─", "┼", "┤"},
+		bottom: ruleStyle{"└", "─", "┴", "┘"},
+		v:      "│",
+	},
+}
+
+// appendRule appends to line a rule spanning columns of the given widths,
+// each of which has pads[i] characters of padding on each side.
+func (rs ruleStyle) appendRule(line []byte, widths, pads []int) []byte {
+	line = append(line, rs.left...)
+	for i, w := range widths {
+		line = append(line, strings.Repeat(rs.fill, w+2*pads[i])...)
+		if i < len(widths)-1 {
+			line = append(line, rs.cross...)
+		}
+	}
+	return append(line, rs.right...)
+}
@@ -0,0 +1,118 @@
+package tabular
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBorderASCII(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII})
+	b.AddRow("name", Right("count"), "note")
+	b.AddRow("a", Right(1), "first")
+	b.AddRow("bb", Right(22))
+	testOutput(t, b, `
++------+-------+-------+
+| name | count | note  |
+| a    |     1 | first |
+| bb   |    22 |       |
++------+-------+-------+
+`)
+}
+
+func TestBorderUnicode(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderUnicode})
+	b.AddRow("ñandú", Center("x"), "☃")
+	b.AddRow("liberté", Right("égalité"), "fraternité")
+	b.AddRow("ragged")
+	testOutput(t, b, `
+┌─────────┬─────────┬────────────┐
+│ ñandú   │    x    │ ☃          │
+│ liberté │ égalité │ fraternité │
+│ ragged  │         │            │
+└─────────┴─────────┴────────────┘
+`)
+}
+
+func TestBorderPaddingLinesUp(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', Border: BorderUnicode})
+	b.SetHeader("ñandú", Right("n"), "note")
+	b.AddRow("liberté", Right(1), "☃")
+	b.AddRule()
+	b.AddRow("wide cell here", Right(22))
+	b.SetFooter("total", Right(23))
+	testOutput(t, b, `
+┌────────────────┬────┬──────┐
+│.ñandú..........│..n.│.note.│
+├────────────────┼────┼──────┤
+│.liberté........│..1.│.☃....│
+├────────────────┼────┼──────┤
+│.wide cell here.│.22.│......│
+├────────────────┼────┼──────┤
+│.total..........│.23.│......│
+└────────────────┴────┴──────┘
+`)
+	// Every line has its vertical separators and rule junctions in the
+	// same columns: each rule segment is as long as the column plus the
+	// padding on both sides.
+	var want []int
+	for i, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		var got []int
+		for j, r := range []rune(line) {
+			if strings.ContainsRune("┌┬┐├┼┤└┴┘│", r) {
+				got = append(got, j)
+			}
+		}
+		if i == 0 {
+			want = got
+			continue
+		}
+		if len(got) != len(want) {
+			t.Fatalf("line %d (%q): separators at %v; want %v", i, line, got, want)
+		}
+		for k := range got {
+			if got[k] != want[k] {
+				t.Errorf("line %d (%q): separators at %v; want %v", i, line, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestBorderNoPadding(t *testing.T) {
+	b := New(Options{PadChar: '.', Border: BorderASCII})
+	b.AddRow("a", "bcd")
+	b.AddRow(Right("ef"), "g")
+	testOutput(t, b, `
++--+---+
+|a.|bcd|
+|ef|g..|
++--+---+
+`)
+}
+
+func TestBorderSpanTruncated(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII})
+	b.AddRow("a", "b")
+	b.AddSpanRow("a very long span row")
+	testOutput(t, b, `
++---+---+
+| a | b |
+| a ve… |
++---+---+
+`)
+}
+
+func TestBorderEmpty(t *testing.T) {
+	testOutput(t, New(Options{Border: BorderUnicode}), "")
+
+	b := New(Options{Border: BorderASCII})
+	b.AddSpanRow("section")
+	testOutput(t, b, `
+section
+`)
+	if got, want := b.Layout(), [][]CellPosition{{{Line: 0, Start: 0, Width: 7, Height: 1}}}; !cmp.Equal(got, want) {
+		t.Errorf("Layout: got %v; want %v", got, want)
+	}
+}
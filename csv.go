@@ -0,0 +1,62 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes the buffered rows as CSV records, starting with the
+// header and ending with the footer, if they are set. Each record contains
+// the cells of a row as formatted by AddRow, without any alignment,
+// truncation, or wrapping, so records may have differing numbers of
+// fields. Rules added by AddRule and blank rows added by AddBlankRow are
+// omitted.
+func (b *Buffer) WriteCSV(w io.Writer) (int64, error) {
+	defer b.lock()()
+	cw := &countWriter{w: w}
+	cr := csv.NewWriter(cw)
+	var record []string
+	write := func(row []cell) error {
+		record = record[:0]
+		for _, c := range row {
+			s := c.s
+			if b.opts.StripCSIForCSV {
+				s = b.opts.stripEscapes(s)
+			}
+			record = append(record, s)
+		}
+		return cr.Write(record)
+	}
+	if b.header != nil {
+		if err := write(b.header); err != nil {
+			return cw.n, err
+		}
+	}
+	for _, r := range b.rows {
+		if r.kind == rowRule || r.kind == rowBlank {
+			continue
+		}
+		if err := write(r.cells); err != nil {
+			return cw.n, err
+		}
+	}
+	if b.footer != nil {
+		if err := write(b.footer); err != nil {
+			return cw.n, err
+		}
+	}
+	cr.Flush()
+	return cw.n, cr.Error()
+}
+
+// countWriter is an io.Writer that counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
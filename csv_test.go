@@ -0,0 +1,60 @@
+package tabular
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCSV(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', MaxWidth: 4})
+	b.AddRow("name", Right("count"), "note")
+	b.AddRow("a,b", 1, `say "hi"`)
+	b.AddRow("liberté", Right(22))
+	b.AddRow("two\nlines", Center("\x1b[31mred\x1b[0m"), "", "extra")
+	testCSV(t, b, `
+name,count,note
+"a,b",1,"say ""hi"""
+liberté,22
+"two
+lines",`+"\x1b[31mred\x1b[0m"+`,,extra
+`)
+}
+
+func TestCSVStripCSI(t *testing.T) {
+	b := New(Options{StripCSIForCSV: true})
+	b.AddRow("\x1b[1mbold\x1b[0m", "plain")
+	testCSV(t, b, `
+bold,plain
+`)
+}
+
+func TestCSVHeaderFooter(t *testing.T) {
+	b := New(Options{})
+	b.SetHeader("name", "count")
+	b.AddRow("a", 1)
+	b.SetFooter("total", 1)
+	testCSV(t, b, `
+name,count
+a,1
+total,1
+`)
+}
+
+func testCSV(t *testing.T, b *Buffer, want string) {
+	t.Helper()
+	want = strings.TrimPrefix(want, "\n")
+	var buf bytes.Buffer
+	n, err := b.WriteCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteCSV returned n=%d; wrote %d bytes", n, buf.Len())
+	}
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("wrong output (-got, +want):\n%s", diff)
+	}
+}
@@ -1,5 +1,11 @@
 module github.com/cespare/tabular
 
-go 1.17
+go 1.23
 
-require github.com/google/go-cmp v0.5.7
+require (
+	github.com/google/go-cmp v0.5.7
+	golang.org/x/term v0.13.0
+	golang.org/x/text v0.13.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,80 @@
+package tabular
+
+import (
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteHTML writes the buffered rows as an HTML table. The header and
+// footer, if set, are written in thead and tfoot sections. Each cell has a
+// text-align style matching its alignment. Cell contents are HTML-escaped,
+// ANSI CSI and OSC sequences are removed, and newlines become <br>
+// elements. Rules added by AddRule and blank rows added by AddBlankRow are
+// omitted.
+func (b *Buffer) WriteHTML(w io.Writer) (int64, error) {
+	defer b.lock()()
+	var buf []byte
+	ncol := b.numColumns()
+	appendCell := func(tag string, c cell, span int) {
+		buf = append(buf, '<')
+		buf = append(buf, tag...)
+		if span > 1 {
+			buf = append(buf, ` colspan="`...)
+			buf = strconv.AppendInt(buf, int64(span), 10)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, ` style="text-align:`...)
+		switch c.align {
+		case AlignLeft:
+			buf = append(buf, "left"...)
+		case AlignRight, AlignDecimal:
+			buf = append(buf, "right"...)
+		case AlignCenter:
+			buf = append(buf, "center"...)
+		case AlignJustify:
+			buf = append(buf, "justify"...)
+		}
+		buf = append(buf, `">`...)
+		s := html.EscapeString(b.opts.stripEscapes(c.s))
+		buf = append(buf, strings.ReplaceAll(s, "\n", "<br>")...)
+		buf = append(buf, "</"...)
+		buf = append(buf, tag...)
+		buf = append(buf, '>')
+	}
+	appendRow := func(tag string, cells []cell) {
+		buf = append(buf, "<tr>"...)
+		for _, c := range cells {
+			appendCell(tag, c, 1)
+		}
+		buf = append(buf, "</tr>\n"...)
+	}
+
+	buf = append(buf, "<table>\n"...)
+	if b.header != nil {
+		buf = append(buf, "<thead>\n"...)
+		appendRow("th", b.header)
+		buf = append(buf, "</thead>\n"...)
+	}
+	buf = append(buf, "<tbody>\n"...)
+	for _, r := range b.rows {
+		switch r.kind {
+		case rowCells:
+			appendRow("td", r.cells)
+		case rowSpan:
+			buf = append(buf, "<tr>"...)
+			appendCell("td", r.cells[0], ncol)
+			buf = append(buf, "</tr>\n"...)
+		}
+	}
+	buf = append(buf, "</tbody>\n"...)
+	if b.footer != nil {
+		buf = append(buf, "<tfoot>\n"...)
+		appendRow("td", b.footer)
+		buf = append(buf, "</tfoot>\n"...)
+	}
+	buf = append(buf, "</table>\n"...)
+	n, err := w.Write(buf)
+	return int64(n), err
+}
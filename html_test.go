@@ -0,0 +1,58 @@
+package tabular
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTML(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.'})
+	b.SetHeader("name", Right("count"))
+	b.AddSpanRow(Center("<fruit>"))
+	b.AddRow("apples & pears", Right(3))
+	b.AddRow("\x1b[31mcherry\x1b[0m", 12, "two\nlines")
+	b.SetFooter("total", Right(15))
+	testHTML(t, b, `
+<table>
+<thead>
+<tr><th style="text-align:left">name</th><th style="text-align:right">count</th></tr>
+</thead>
+<tbody>
+<tr><td colspan="3" style="text-align:center">&lt;fruit&gt;</td></tr>
+<tr><td style="text-align:left">apples &amp; pears</td><td style="text-align:right">3</td></tr>
+<tr><td style="text-align:left">cherry</td><td style="text-align:left">12</td><td style="text-align:left">two<br>lines</td></tr>
+</tbody>
+<tfoot>
+<tr><td style="text-align:left">total</td><td style="text-align:right">15</td></tr>
+</tfoot>
+</table>
+`)
+}
+
+func TestHTMLEmpty(t *testing.T) {
+	testHTML(t, New(Options{}), `
+<table>
+<tbody>
+</tbody>
+</table>
+`)
+}
+
+func testHTML(t *testing.T, b *Buffer, want string) {
+	t.Helper()
+	want = strings.TrimPrefix(want, "\n")
+	var buf bytes.Buffer
+	n, err := b.WriteHTML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteHTML returned n=%d; wrote %d bytes", n, buf.Len())
+	}
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("wrong output (-got, +want):\n%s", diff)
+	}
+}
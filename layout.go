@@ -0,0 +1,419 @@
+package tabular
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A layout is the arrangement of a Buffer's cells into the columns of the
+// table that is displayed.
+type layout struct {
+	// cols holds, for each displayed column, the index of the column of
+	// the Buffer it shows, or -1 for the row number column.
+	cols   []int
+	header []cell // nil if there is no header
+	footer []cell // nil if there is no footer
+	rows   []row
+	widths []int // width of each displayed column
+	pads   []int // padding before each displayed column
+	// padRunes holds the pad character of each displayed column.
+	padRunes []rune
+	// intWidths and fracWidths hold, for each displayed column, the
+	// widest parts of its decimal-aligned cells before and after (and
+	// including) the decimal point.
+	intWidths  []int
+	fracWidths []int
+	// sticky holds the widths to record in Buffer.sticky if the table is
+	// written (see writeLayout).
+	sticky []int
+	// narrowed records which displayed columns were made narrower than
+	// their cells to fit MaxTableWidth.
+	narrowed []bool
+	// If recordPositions is set, render records the positions of the
+	// cells of each row that it writes in positions (see Buffer.Layout).
+	recordPositions bool
+	positions       [][]CellPosition
+}
+
+func (b *Buffer) layout() *layout {
+	l := &layout{header: b.header, footer: b.footer, rows: b.rows}
+	order := b.columnOrder()
+	if order != nil {
+		l.header = arrange(b.header, order)
+		l.footer = arrange(b.footer, order)
+		l.rows = make([]row, len(b.rows))
+		for i, r := range b.rows {
+			if r.kind == rowCells {
+				r.cells = arrange(r.cells, order)
+			}
+			l.rows[i] = r
+		}
+	}
+	if b.opts.RowNumbers {
+		rows := l.rows
+		l.rows = make([]row, len(rows))
+		var i int
+		for j, r := range rows {
+			if r.kind == rowCells {
+				i++
+				n := strconv.Itoa(i)
+				r.cells = append([]cell{{s: n, wc: len(n), align: AlignRight}}, r.cells...)
+			}
+			l.rows[j] = r
+		}
+		if l.header != nil {
+			l.header = append([]cell{{s: "#", wc: 1, align: AlignRight}}, l.header...)
+		}
+		if l.footer != nil {
+			l.footer = append([]cell{{}}, l.footer...)
+		}
+	}
+
+	l.header = rightAlignDecimals(l.header)
+	l.footer = rightAlignDecimals(l.footer)
+
+	ncol := len(l.header)
+	if len(l.footer) > ncol {
+		ncol = len(l.footer)
+	}
+	for _, r := range l.rows {
+		if r.kind == rowCells && len(r.cells) > ncol {
+			ncol = len(r.cells)
+		}
+	}
+	if b.opts.RowNumbers {
+		l.cols = append(l.cols, -1)
+	}
+	if order != nil {
+		l.cols = append(l.cols, order...)
+	}
+	for i := len(l.cols); i < ncol; i++ {
+		if b.opts.RowNumbers {
+			l.cols = append(l.cols, i-1)
+		} else {
+			l.cols = append(l.cols, i)
+		}
+	}
+	l.cols = l.cols[:ncol]
+
+	l.widths = make([]int, ncol)
+	l.intWidths = make([]int, ncol)
+	l.fracWidths = make([]int, ncol)
+	measure := func(row []cell) {
+		for i, c := range row {
+			if c.wc > l.widths[i] {
+				l.widths[i] = c.wc
+			}
+			if c.align != AlignDecimal {
+				continue
+			}
+			for _, line := range b.lines(c) {
+				iw, fw := b.opts.decimalWidths(line)
+				if iw > l.intWidths[i] {
+					l.intWidths[i] = iw
+				}
+				if fw > l.fracWidths[i] {
+					l.fracWidths[i] = fw
+				}
+			}
+		}
+	}
+	measure(l.header)
+	measure(l.footer)
+	for _, r := range l.rows {
+		if r.kind == rowCells {
+			measure(r.cells)
+		}
+	}
+	for i := range l.widths {
+		if w := l.intWidths[i] + l.fracWidths[i]; w > l.widths[i] {
+			l.widths[i] = w
+		}
+	}
+	if len(b.opts.CollapseColumns) > 0 {
+		l.collapse(b.opts.CollapseColumns)
+	}
+	l.pads = make([]int, ncol)
+	l.padRunes = make([]rune, ncol)
+	for i, col := range l.cols {
+		if col >= 0 && col < len(b.widths) && b.widths[col] >= 0 {
+			if l.widths[i] > b.widths[col] && b.opts.TruncateFixedWidths {
+				if l.narrowed == nil {
+					l.narrowed = make([]bool, ncol)
+				}
+				l.narrowed[i] = true
+			}
+			l.widths[i] = b.widths[col]
+		} else if min := b.columnMinWidth(col); l.widths[i] < min {
+			l.widths[i] = min
+		}
+		l.pads[i] = b.columnPadding(col)
+		l.padRunes[i] = b.columnPadRune(col)
+	}
+	if b.opts.StickyWidths {
+		l.sticky = append([]int(nil), b.sticky...)
+		for i, w := range l.widths {
+			if i == len(l.sticky) {
+				l.sticky = append(l.sticky, w)
+			} else if l.sticky[i] > w {
+				l.widths[i] = l.sticky[i]
+			} else {
+				l.sticky[i] = w
+			}
+		}
+	}
+	if b.opts.MaxTableWidth > 0 {
+		l.narrow(b, b.opts.MaxTableWidth)
+	}
+	return l
+}
+
+// writeLayout returns b.layout() for a table that is written, recording
+// the widths of its columns for opts.StickyWidths. Methods that only
+// inspect the table, such as Size and Grid, use b.layout() instead.
+func (b *Buffer) writeLayout() *layout {
+	l := b.layout()
+	if l.sticky != nil {
+		b.sticky = l.sticky
+	}
+	return l
+}
+
+// collapse empties each cell of the given columns of the Buffer whose text
+// is the same as that of the cell above it. It is done after measuring the
+// cells, so that the widths of the columns are unchanged.
+func (l *layout) collapse(cols []int) {
+	collapsed := make(map[int]bool, len(cols))
+	for _, col := range cols {
+		collapsed[col] = true
+	}
+	rows := make([]row, len(l.rows))
+	var prev []cell
+	for i, r := range l.rows {
+		rows[i] = r
+		if r.kind != rowCells {
+			prev = nil
+			continue
+		}
+		var copied bool
+		for j, c := range r.cells {
+			if !collapsed[l.cols[j]] || j >= len(prev) || c.s != prev[j].s {
+				continue
+			}
+			if !copied {
+				rows[i].cells = append([]cell(nil), r.cells...)
+				copied = true
+			}
+			rows[i].cells[j] = cell{align: c.align}
+		}
+		prev = r.cells
+	}
+	l.rows = rows
+}
+
+// columnOrder returns the columns of b in the order in which they are
+// displayed, or nil if they are displayed in their usual order.
+func (b *Buffer) columnOrder() []int {
+	if b.opts.ColumnOrder == nil && !b.opts.ReverseColumns && len(b.opts.HideColumns) == 0 {
+		return nil
+	}
+	cols := b.opts.ColumnOrder
+	if cols == nil {
+		cols = make([]int, b.numColumns())
+		for i := range cols {
+			cols[i] = i
+		}
+	}
+	hidden := make(map[int]bool, len(b.opts.HideColumns))
+	for _, col := range b.opts.HideColumns {
+		hidden[col] = true
+	}
+	order := make([]int, 0, len(cols))
+	for i := range cols {
+		col := cols[i]
+		if b.opts.ReverseColumns {
+			col = cols[len(cols)-1-i]
+		}
+		if !hidden[col] {
+			order = append(order, col)
+		}
+	}
+	return order
+}
+
+// arrange returns the cells of row in the given order of columns. Columns
+// that the row doesn't have are empty, except that the result ends with
+// the last cell that the row has.
+func arrange(row []cell, order []int) []cell {
+	if row == nil {
+		return nil
+	}
+	cells := make([]cell, 0, len(order))
+	var end int
+	for _, col := range order {
+		if col < len(row) {
+			cells = append(cells, row[col])
+			end = len(cells)
+		} else {
+			cells = append(cells, cell{})
+		}
+	}
+	return cells[:end]
+}
+
+// rightAlignDecimals returns row, or a copy of it if needed, with its
+// decimal-aligned cells right-aligned instead. Decimal points are lined up
+// in the rows of the table only; a header or footer is right-aligned so
+// that it doesn't widen its column.
+func rightAlignDecimals(row []cell) []cell {
+	for i, c := range row {
+		if c.align != AlignDecimal {
+			continue
+		}
+		row = append([]cell(nil), row...)
+		for j := i; j < len(row); j++ {
+			if row[j].align == AlignDecimal {
+				row[j].align = AlignRight
+			}
+		}
+		break
+	}
+	return row
+}
+
+// narrow reduces the widths of the widest columns, one column at a time, so
+// that the table is no wider than max, if possible.
+func (l *layout) narrow(b *Buffer, max int) {
+	if l.narrowed == nil {
+		l.narrowed = make([]bool, len(l.widths))
+	}
+	for excess := l.tableWidth(&b.opts) - max; excess > 0; excess-- {
+		widest := -1
+		for i, w := range l.widths {
+			if l.cols[i] < 0 {
+				continue // never narrow the row numbers
+			}
+			min := b.columnMinWidth(l.cols[i])
+			if min < 1 {
+				min = 1
+			}
+			if w > min && (widest < 0 || w > l.widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		l.widths[widest]--
+		l.narrowed[widest] = true
+	}
+}
+
+// cellLines returns the lines of c, a cell in displayed column j, as
+// returned by Buffer.lines and truncated if the column was narrowed.
+func (l *layout) cellLines(b *Buffer, c cell, j int) []string {
+	lines := b.lines(c)
+	if j < len(l.narrowed) && l.narrowed[j] && c.wc > l.widths[j] {
+		for k, s := range lines {
+			lines[k] = b.opts.truncate(s, l.widths[j])
+		}
+	}
+	return lines
+}
+
+// spanWidth returns the width of a span row: the width of all the columns
+// and the padding between them (or, with a border, the vertical separators
+// and the padding inside them).
+func (l *layout) spanWidth(o *Options) int {
+	border := borderStyles[o.Border]
+	var n int
+	for i, w := range l.widths {
+		n += w
+		if i > 0 {
+			n += l.pads[i]
+			if border != nil {
+				n += l.pads[i-1] + o.cellWidth(border.v)
+			} else if o.ColumnSeparator != "" {
+				n += o.cellWidth(o.ColumnSeparator) + l.pads[i]
+			}
+		}
+	}
+	return n
+}
+
+// tableWidth returns the width of the whole table, including any border.
+func (l *layout) tableWidth(o *Options) int {
+	if len(l.widths) == 0 {
+		return 0
+	}
+	n := l.spanWidth(o)
+	if border := borderStyles[o.Border]; border != nil {
+		n += 2*o.cellWidth(border.v) + l.pads[0] + l.pads[len(l.pads)-1]
+	}
+	return n
+}
+
+func (b *Buffer) columnMinWidth(col int) int {
+	if col >= 0 && col < len(b.opts.ColumnMinWidth) {
+		return b.opts.ColumnMinWidth[col]
+	}
+	return b.opts.MinWidth
+}
+
+func (b *Buffer) columnPadding(col int) int {
+	n := b.opts.Padding
+	if col >= 0 && col < len(b.opts.ColumnPadding) {
+		n = b.opts.ColumnPadding[col]
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (b *Buffer) columnPadRune(col int) rune {
+	if col >= 0 && col < len(b.opts.ColumnPadChar) && b.opts.ColumnPadChar[col] != 0 {
+		return b.opts.ColumnPadChar[col]
+	}
+	return b.opts.padRune()
+}
+
+// decimalWidths returns the widths of the parts of s before and after (and
+// including) its first decimal point.
+func (o *Options) decimalWidths(s string) (iw, fw int) {
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return o.cellWidth(s), 0
+	}
+	return o.cellWidth(s[:i]), o.cellWidth(s[i:])
+}
+
+// decimalPadding returns the padding to add to the left and right of text,
+// a line of a decimal-aligned cell in displayed column j, so that its
+// decimal point lines up with the others in the column. Any extra width in
+// the column goes on the left. If the column was narrowed so that the
+// decimal points can't line up, the padding is reduced (starting on the
+// right) so that the text still fits in the column.
+func (l *layout) decimalPadding(o *Options, j int, text string) (left, right int) {
+	iw, fw := o.decimalWidths(text)
+	left = l.widths[j] - l.fracWidths[j] - iw
+	right = l.fracWidths[j] - fw
+	if left < 0 {
+		left = 0
+	}
+	if right < 0 {
+		right = 0
+	}
+	if excess := left + iw + fw + right - l.widths[j]; excess > 0 {
+		if excess > right {
+			left -= excess - right
+			if left < 0 {
+				left = 0
+			}
+			right = 0
+		} else {
+			right -= excess
+		}
+	}
+	return left, right
+}
@@ -0,0 +1,86 @@
+package tabular
+
+import (
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the buffered rows as a GitHub-flavored Markdown
+// table. The header set by SetHeader, or the first row if there is no
+// header, becomes the Markdown table header, and the alignment of each
+// column is taken from the alignment of its cell in the header. Markdown
+// tables have no footer, so a footer set by SetFooter is written as the
+// last row. Rules added by AddRule and blank rows added by AddBlankRow are
+// omitted. Pipe characters in cells are escaped, and newlines become <br>
+// elements. The Padding and PadChar options are ignored.
+func (b *Buffer) WriteMarkdown(w io.Writer) (int64, error) {
+	defer b.lock()()
+	var rows [][]cell
+	if b.header != nil {
+		rows = append(rows, b.header)
+	}
+	for _, r := range b.rows {
+		if r.kind == rowCells || r.kind == rowSpan {
+			rows = append(rows, r.cells)
+		}
+	}
+	if b.footer != nil {
+		rows = append(rows, b.footer)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	var ncol int
+	for _, row := range rows {
+		if len(row) > ncol {
+			ncol = len(row)
+		}
+	}
+	var line []byte
+	var written int64
+	writeLine := func() error {
+		line = append(line, "|\n"...)
+		n, err := w.Write(line)
+		written += int64(n)
+		line = line[:0]
+		return err
+	}
+	for i, row := range rows {
+		for j := 0; j < ncol; j++ {
+			line = append(line, "| "...)
+			if j < len(row) {
+				for k, s := range strings.Split(row[j].s, "\n") {
+					if k > 0 {
+						line = append(line, "<br>"...)
+					}
+					line = append(line, strings.ReplaceAll(b.text(s), "|", `\|`)...)
+				}
+			}
+			line = append(line, ' ')
+		}
+		if err := writeLine(); err != nil {
+			return written, err
+		}
+		if i > 0 {
+			continue
+		}
+		for j := 0; j < ncol; j++ {
+			a := AlignLeft
+			if j < len(row) {
+				a = row[j].align
+			}
+			switch a {
+			case AlignLeft, AlignJustify:
+				line = append(line, "| :--- "...)
+			case AlignRight, AlignDecimal:
+				line = append(line, "| ---: "...)
+			case AlignCenter:
+				line = append(line, "| :---: "...)
+			}
+		}
+		if err := writeLine(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,88 @@
+package tabular
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarkdown(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.'})
+	b.AddRow("name", Right("count"), Center("state"))
+	b.AddRow("a|b", 1, "on")
+	b.AddRow("c", 22)
+	testMarkdown(t, b, `
+| name | count | state |
+| :--- | ---: | :---: |
+| a\|b | 1 | on |
+| c | 22 |  |
+`)
+}
+
+func TestMarkdownMultiline(t *testing.T) {
+	b := New(Options{})
+	b.SetHeader("name", "address")
+	b.AddRow("ann", "1 Main St\nSpringfield")
+	testMarkdown(t, b, `
+| name | address |
+| :--- | :--- |
+| ann | 1 Main St<br>Springfield |
+`)
+
+	b = New(Options{MaxWidth: 4, TabWidth: 4})
+	b.AddRow("ab\tc\nd\te")
+	testMarkdown(t, b, `
+| ab …<br>d  … |
+| :--- |
+`)
+	b = New(Options{MaxWidth: 5, TabWidth: 4})
+	b.AddRow("ab\tc\nd\te")
+	testMarkdown(t, b, `
+| ab  c<br>d   e |
+| :--- |
+`)
+}
+
+func TestMarkdownColumnAlign(t *testing.T) {
+	b := New(Options{AlignRight: true, ColumnAlign: []Align{AlignLeft}})
+	b.AddRow("x", "x²", Left("note"))
+	b.AddRow(2, 4, "even")
+	testMarkdown(t, b, `
+| x | x² | note |
+| :--- | ---: | :--- |
+| 2 | 4 | even |
+`)
+}
+
+func TestMarkdownHeader(t *testing.T) {
+	b := New(Options{})
+	b.SetHeader("name", Right("count"))
+	b.AddRow("a", 1)
+	testMarkdown(t, b, `
+| name | count |
+| :--- | ---: |
+| a | 1 |
+`)
+}
+
+func TestMarkdownEmpty(t *testing.T) {
+	testMarkdown(t, New(Options{}), "")
+}
+
+func testMarkdown(t *testing.T, b *Buffer, want string) {
+	t.Helper()
+	want = strings.TrimPrefix(want, "\n")
+	var buf bytes.Buffer
+	n, err := b.WriteMarkdown(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteMarkdown returned n=%d; wrote %d bytes", n, buf.Len())
+	}
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("wrong output (-got, +want):\n%s", diff)
+	}
+}
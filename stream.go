@@ -0,0 +1,41 @@
+package tabular
+
+import "io"
+
+// A Stream writes rows as a text table as they are added, rather than
+// buffering them, using fixed column widths given in advance. Create a
+// Stream with NewStream.
+type Stream struct {
+	w io.Writer
+	b *Buffer
+}
+
+// NewStream returns a Stream that writes to w, formatting rows with opts.
+// The width of each column is given by the corresponding entry of widths
+// instead of being computed from the cells, except for columns beyond the
+// end of widths, whose widths are those of the cells in each row. Cells
+// wider than their columns overflow them unless opts.TruncateFixedWidths is
+// set.
+//
+// A Stream writes only rows, so opts.Border, opts.RowNumbers, and the
+// options that depend on the whole table (MaxTableWidth and FitTerminal)
+// are ignored.
+func NewStream(w io.Writer, widths []int, opts Options) *Stream {
+	opts.Border = BorderNone
+	opts.RowNumbers = false
+	opts.MaxTableWidth = 0
+	opts.FitTerminal = false
+	b := New(opts)
+	b.widths = append([]int(nil), widths...)
+	return &Stream{w: w, b: b}
+}
+
+// WriteRow formats a row of values, as by Buffer.AddRow, and writes it
+// immediately.
+func (s *Stream) WriteRow(vs ...interface{}) error {
+	s.b.rows = append(s.b.rows[:0], row{cells: s.b.makeRow(vs)})
+	return s.b.render(s.b.writeLayout(), func(line []byte) error {
+		_, err := s.w.Write(line)
+		return err
+	})
+}
@@ -0,0 +1,52 @@
+package tabular
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStream(&buf, []int{4, 5}, Options{Padding: 1, PadChar: '.'})
+	want := []string{
+		"a........1.x\n",
+		"bb......22.yy\n",
+		"c..........overflow\n",
+		"d......3.5\n",
+	}
+	rows := [][]interface{}{
+		{"a", Right(1), "x"},
+		{"bb", Right(22), "yy"},
+		{"c", "", "overflow"},
+		{"d", Right(3.5)},
+	}
+	var wrote string
+	for i, vs := range rows {
+		if err := s.WriteRow(vs...); err != nil {
+			t.Fatal(err)
+		}
+		wrote += want[i]
+		if got := buf.String(); got != wrote {
+			t.Fatalf("after row %d: got\n%s\nwant\n%s", i, got, wrote)
+		}
+	}
+}
+
+func TestStreamOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		truncate bool
+		want     string
+	}{
+		{false, "abcdef.x\nab...y\n"},
+		{true, "abc….x\nab...y\n"},
+	} {
+		var buf bytes.Buffer
+		s := NewStream(&buf, []int{4}, Options{Padding: 1, PadChar: '.', TruncateFixedWidths: tt.truncate})
+		s.WriteRow("abcdef", "x")
+		s.WriteRow("ab", "y")
+		if got := buf.String(); got != tt.want {
+			t.Errorf("TruncateFixedWidths=%t: got\n%s\nwant\n%s", tt.truncate, got, strings.TrimSuffix(tt.want, "\n"))
+		}
+	}
+}
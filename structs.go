@@ -0,0 +1,111 @@
+package tabular
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AddStructs adds a row to b for each element of structs, which must be a
+// slice or array of structs or of pointers to structs, and sets the header
+// to the names of the columns. Each exported field of the struct type is a
+// column, in the order of the fields, labeled with the field's name.
+// Unexported fields are skipped.
+//
+// The label and alignment of a column may be given by a struct tag of the
+// form `tabular:"name,align"`, where align is one of right, left, center,
+// decimal, or justify. Either part may be omitted, as in `tabular:",right"`.
+// The alignment applies to the header as well as the column's cells, as if
+// each were wrapped in Right (and so on), except that the header of a
+// decimal column is aligned to the right. A field with the tag `tabular:"-"`
+// is skipped. A nil pointer adds an empty row.
+//
+// AddStructs panics if structs is not a slice or array of structs or of
+// pointers to structs, or if a struct tag gives an alignment other than
+// those listed above.
+func AddStructs(b *Buffer, structs interface{}) {
+	v := reflect.ValueOf(structs)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		panic(fmt.Sprintf("tabular: AddStructs called with %T, not a slice", structs))
+	}
+	t := v.Type().Elem()
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tabular: AddStructs called with %T, not a slice of structs", structs))
+	}
+	var fields []structField
+	var header []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		f, ok := parseStructField(t.Field(i))
+		if !ok {
+			continue
+		}
+		fields = append(fields, f)
+		header = append(header, f.headerMark(f.name))
+	}
+	b.SetHeader(header...)
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		if ptr {
+			if e.IsNil() {
+				b.AddRow()
+				continue
+			}
+			e = e.Elem()
+		}
+		row := make([]interface{}, len(fields))
+		for j, f := range fields {
+			row[j] = f.mark(e.Field(f.index).Interface())
+		}
+		b.AddRow(row...)
+	}
+}
+
+// A structField is a field of a struct type that is a column of the table
+// made by AddStructs.
+type structField struct {
+	index int
+	name  string
+	// mark and headerMark are the alignment markers, such as Right, for
+	// the cells and the header.
+	mark       func(interface{}) interface{}
+	headerMark func(interface{}) interface{}
+}
+
+// parseStructField returns the column for field f, or false if f is not
+// displayed.
+func parseStructField(f reflect.StructField) (structField, bool) {
+	tag, hasTag := f.Tag.Lookup("tabular")
+	if f.PkgPath != "" || tag == "-" {
+		return structField{}, false
+	}
+	identity := func(v interface{}) interface{} { return v }
+	sf := structField{index: f.Index[0], name: f.Name, mark: identity, headerMark: identity}
+	if !hasTag {
+		return sf, true
+	}
+	name, align, _ := strings.Cut(tag, ",")
+	if name != "" {
+		sf.name = name
+	}
+	switch align {
+	case "":
+	case "right":
+		sf.mark, sf.headerMark = Right, Right
+	case "left":
+		sf.mark, sf.headerMark = Left, Left
+	case "center":
+		sf.mark, sf.headerMark = Center, Center
+	case "decimal":
+		// The header has no decimal point to line up.
+		sf.mark, sf.headerMark = Decimal, Right
+	case "justify":
+		sf.mark, sf.headerMark = Justify, Justify
+	default:
+		panic(fmt.Sprintf("tabular: unknown alignment %q in tag of field %s", align, f.Name))
+	}
+	return sf, true
+}
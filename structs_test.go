@@ -0,0 +1,56 @@
+package tabular
+
+import "testing"
+
+func TestAddStructs(t *testing.T) {
+	type fruit struct {
+		Name   string  `tabular:"name"`
+		Count  int     `tabular:"qty,right"`
+		Price  float64 `tabular:",decimal"`
+		secret string
+		Notes  string `tabular:"-"`
+	}
+	b := New(Options{Padding: 1, PadChar: '.'})
+	AddStructs(b, []fruit{
+		{"apple", 1, 0.5, "x", "red"},
+		{"banana", 22, 12.25, "y", "yellow"},
+	})
+	testOutput(t, b, `
+name...qty.Price
+------.---.-----
+apple....1..0.5
+banana..22.12.25
+`)
+
+	type point struct {
+		X, Y int
+	}
+	b = New(Options{Padding: 1, PadChar: '.'})
+	AddStructs(b, []*point{{1, 2}, nil, {30, 40}})
+	testOutput(t, b, `
+X..Y
+--.--
+1..2
+
+30.40
+`)
+}
+
+func TestAddStructsPanics(t *testing.T) {
+	for _, v := range []interface{}{
+		3,
+		[]int{1},
+		[]struct {
+			A int `tabular:"a,sideways"`
+		}{{1}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("AddStructs(%#v) didn't panic", v)
+				}
+			}()
+			AddStructs(New(Options{}), v)
+		}()
+	}
+}
@@ -0,0 +1,69 @@
+package tabular
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// A TabbedWriter is an io.Writer that adds tab-delimited lines of text
+// written to it as rows of a Buffer, as a replacement for text/tabwriter.
+// Each cell of a line is terminated by a tab or by the end of the line; a
+// tab at the end of a line terminates the last cell, rather than beginning
+// an empty one. As with a tabwriter.Writer, the table is written to the
+// underlying io.Writer by Flush.
+type TabbedWriter struct {
+	*Buffer
+	w       io.Writer
+	partial []byte // the start of a line that has not been written in full
+}
+
+// NewFromTabbed returns a TabbedWriter that writes to w a table formatted
+// with opts.
+func NewFromTabbed(w io.Writer, opts Options) *TabbedWriter {
+	return &TabbedWriter{Buffer: New(opts), w: w}
+}
+
+// Write adds each complete line of p, together with any incomplete line
+// written previously, as a row of the Buffer. It always returns len(p),
+// nil.
+func (t *TabbedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		t.partial = append(t.partial, p[:i]...)
+		t.addLine()
+		p = p[i+1:]
+	}
+	t.partial = append(t.partial, p...)
+	return n, nil
+}
+
+// Flush adds the final line written to t, if it did not end with a newline,
+// writes the table to the underlying io.Writer, as by WriteTo, and resets
+// the Buffer, so that text written to t afterward forms a new table.
+func (t *TabbedWriter) Flush() error {
+	if len(t.partial) > 0 {
+		t.addLine()
+	}
+	_, err := t.Buffer.WriteTo(t.w)
+	t.Reset()
+	return err
+}
+
+func (t *TabbedWriter) addLine() {
+	line := strings.TrimSuffix(string(t.partial), "\r")
+	t.partial = t.partial[:0]
+	var vs []interface{}
+	if line != "" {
+		fields := strings.Split(strings.TrimSuffix(line, "\t"), "\t")
+		vs = make([]interface{}, len(fields))
+		for i, f := range fields {
+			vs[i] = f
+		}
+	}
+	t.AddRow(vs...)
+}
@@ -0,0 +1,52 @@
+package tabular
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestTabbedWriter(t *testing.T) {
+	opts := Options{Padding: 2, PadChar: '.'}
+	var buf bytes.Buffer
+	tw := NewFromTabbed(&buf, opts)
+	fmt.Fprintf(tw, "name\tcount\tnote\n")
+	fmt.Fprintf(tw, "apple\t1\t\n")
+	fmt.Fprintf(tw, "banana\t\tyellow\r\n")
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "kiwi\t333\tsmall\textra\n")
+	fmt.Fprintf(tw, "par")
+	fmt.Fprintf(tw, "tial\t")
+	fmt.Fprintf(tw, "line")
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(opts)
+	b.AddRow("name", "count", "note")
+	b.AddRow("apple", "1")
+	b.AddRow("banana", "", "yellow")
+	b.AddRow()
+	b.AddRow("kiwi", "333", "small", "extra")
+	b.AddRow("partial", "line")
+	if got, want := buf.String(), b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	testOutput(t, b, `
+name.....count..note
+apple....1
+banana..........yellow
+
+kiwi.....333....small...extra
+partial..line
+`)
+
+	buf.Reset()
+	fmt.Fprintf(tw, "a\tb\n")
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "a..b\n"; got != want {
+		t.Errorf("after second Flush: got %q; want %q", got, want)
+	}
+}
@@ -7,16 +7,27 @@
 //   * Inserts padding between cells, not after each cell
 //   * Does not count padding toward min-width
 //   * Does not insert any padding after the last cell of each row
-//   * Allows for per-cell right-alignment
+//   * Allows for per-cell right- and center-alignment
 //   * Omits several lesser-used features
 //
 package tabular
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"unicode/utf8"
+	"sync"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Options configure a Writer.
@@ -25,20 +36,478 @@ type Options struct {
 	Padding    int  // Padding between each cell.
 	PadChar    byte // The character to use for padding.
 	AlignRight bool // Align cells to the rigth by default.
+
+	// PadRune, if nonzero, is used for padding instead of PadChar.
+	// It may be a multibyte character. If its width is more than 1,
+	// padding that is not a multiple of its width is completed with
+	// spaces.
+	PadRune rune
+
+	// ColumnPadChar sets the character used for the padding within each
+	// column and before it (see ColumnPadding) by index. Columns beyond
+	// the end of ColumnPadChar, or whose entry is 0, use PadRune or
+	// PadChar.
+	ColumnPadChar []rune
+
+	// StripeChar, if nonzero, is used instead of the usual pad character
+	// for all the padding of every other row (the second, fourth, and so
+	// on), counting only the rows added by AddRow and similar methods and
+	// not the header, footer, span rows, or rules.
+	StripeChar byte
+
+	// MaxWidth, if positive, is the maximum visible width of a cell.
+	// Longer cells are truncated with an ellipsis (…); see TruncateMode.
+	MaxWidth int
+
+	// TruncateMode selects which part of a cell is replaced with an
+	// ellipsis when it is truncated to fit MaxWidth (or MaxTableWidth or
+	// a fixed column width). By default, the end of the cell is removed.
+	TruncateMode TruncateMode
+
+	// MaxTableWidth, if positive, is the maximum visible width of the
+	// table. If the table would be wider, its widest columns are narrowed,
+	// one column at a time, until it fits, and the cells in them are
+	// truncated with an ellipsis like those longer than MaxWidth.
+	// A column is not narrowed below its minimum width (see
+	// ColumnMinWidth) or 1, and the row number column added by RowNumbers
+	// is not narrowed at all, so the table may still be too wide. Span
+	// rows are not considered.
+	MaxTableWidth int
+
+	// FitTerminal makes WriteTo narrow the table, as for MaxTableWidth, to
+	// fit the width of the terminal when writing to an *os.File that is a
+	// terminal. Otherwise, FitTerminal has no effect.
+	FitTerminal bool
+
+	// TruncateFixedWidths makes cells that are wider than the fixed column
+	// widths set by Buffer.SetColumnWidths or given to NewStream truncated
+	// with an ellipsis, like those longer than MaxWidth. By default, such
+	// cells overflow their columns, pushing the rest of the line to the
+	// right.
+	TruncateFixedWidths bool
+
+	// LockWidthsAfterFirstRow fixes the width of each column, as if by
+	// Buffer.SetColumnWidths, when the first row with any cells is added
+	// by AddRow (or a similar method), to the width of the row's cell or
+	// of the header's cell, if it is wider, expanded to the column's
+	// minimum width. Wider cells in later rows overflow their columns or,
+	// if TruncateFixedWidths is set, are truncated. Columns beyond the end
+	// of the first row have their widths computed as usual. Widths already
+	// set by SetColumnWidths take precedence, and the widths stay fixed
+	// after Reset.
+	LockWidthsAfterFirstRow bool
+
+	// WrapWidth, if positive, is the maximum visible width of a line of
+	// text in a cell. Wider cells are wrapped onto multiple lines, and the
+	// other cells of the row are left blank on the extra lines. ANSI SGR
+	// styles, such as colors, in effect at the end of a line are reset
+	// there and set again at the start of the next line.
+	WrapWidth int
+
+	// WrapMode selects where wrapped lines are broken. By default
+	// (WrapRune), each line is filled up to WrapWidth. With WrapWord,
+	// lines are broken at spaces, which are removed, and a word is only
+	// broken if it is wider than WrapWidth by itself.
+	WrapMode WrapMode
+
+	// VerticalAlign is the vertical alignment of the cells in a row that
+	// takes more than one line (because a cell contains newlines or is
+	// wrapped). The lines of a shorter cell are preceded or followed by
+	// blank lines, as needed, to fill the row. VAlignMiddle places any
+	// extra blank line below the cell.
+	VerticalAlign VAlign
+
+	// TabWidth, if positive, is the distance between tab stops used to
+	// expand tab characters in cells into spaces.
+	TabWidth int
+
+	// CountCSI makes ANSI CSI and OSC sequences count toward the width of
+	// a cell like any other text. By default, they are assumed to be
+	// invisible terminal control sequences (such as colors and OSC 8
+	// hyperlinks) with a width of 0.
+	CountCSI bool
+
+	// StrictCSI limits the CSI sequences that are assumed to be invisible
+	// to well-formed SGR sequences, such as "\x1b[1;31m", which consist of
+	// ESC, '[', digits, semicolons, and colons, and end with 'm'. (OSC
+	// sequences are unaffected.) By default, anything of the general form
+	// of a CSI sequence is invisible, so that a malformed sequence may
+	// take the characters after it with it (in "\x1b[abc", for example,
+	// "\x1b[a" is treated as a sequence). With StrictCSI, the ESC that
+	// begins any other sequence, and the characters after it, are counted
+	// like other text.
+	StrictCSI bool
+
+	// InterpretControls makes carriage returns and backspaces in cells
+	// count as moving back to the start of the line and back by one
+	// column, respectively, when measuring the width of a cell, like in a
+	// terminal. (For example, "abc\rXY" is displayed as "XYc", which has
+	// width 3.) The text itself is written unchanged.
+	InterpretControls bool
+
+	// EastAsianWidth makes East Asian wide and fullwidth characters, as
+	// well as characters whose East Asian width is ambiguous (such as
+	// some arrows and box-drawing characters), count as width 2, as they
+	// are displayed by terminals in CJK locales.
+	EastAsianWidth bool
+
+	// WideChars makes East Asian wide and fullwidth characters (such as
+	// CJK ideographs and most emoji) count as width 2, as most terminals
+	// display them, while characters whose East Asian width is ambiguous
+	// keep width 1. EastAsianWidth implies WideChars.
+	WideChars bool
+
+	// SanitizeControls gives control characters (such as those in the C0
+	// and C1 ranges) and invisible formatting characters (such as the
+	// zero-width space U+200B, the zero-width joiner U+200D, and the soft
+	// hyphen U+00AD) a width of 0, instead of 1. Carriage returns and
+	// backspaces are still handled as described for InterpretControls, if
+	// it is set.
+	SanitizeControls bool
+
+	// NormalizeNFC converts the text of each cell to Unicode Normalization
+	// Form C when it is added, so that a character made of a letter and
+	// combining marks, such as "e\u0301", is replaced by its precomposed
+	// form ("\u00e9") where there is one, and is measured as one column.
+	NormalizeNFC bool
+
+	// GraphemeWidth makes each grapheme cluster, rather than each code
+	// point, count as a single character when measuring widths. For
+	// example, an emoji ZWJ sequence such as 👨‍👩‍👧, a flag made of two
+	// regional indicators, or a letter followed by combining accents is
+	// then measured as one character. A cluster displayed as an emoji,
+	// such as either of the first two examples, counts as width 2, as
+	// terminals display it; other wide characters, such as CJK
+	// ideographs, count as width 2 only with WideChars or EastAsianWidth.
+	GraphemeWidth bool
+
+	// WidthFunc, if non-nil, replaces the package's own measurement of
+	// text. It is given the raw text of a cell, including any ANSI escape
+	// sequences, and returns the number of columns the text occupies.
+	// When truncating, wrapping, or expanding tabs, it is instead given
+	// each piece of the text in turn: escape sequences (unless CountCSI is
+	// set) and code points (or grapheme clusters). Alignment is then only
+	// as correct as WidthFunc.
+	WidthFunc func(string) int
+
+	// RowNumbers adds a column before the others that numbers the rows,
+	// starting at 1. The column is labeled "#" in the header and is
+	// blank in the footer.
+	// Options that configure columns by index ignore the row number
+	// column, using the scalar option (MinWidth, Padding, and so on)
+	// for it instead. The column is not included by WriteCSV, WriteTSV,
+	// WriteMarkdown, or WriteHTML.
+	RowNumbers bool
+
+	// ReverseColumns displays the columns in the reverse order, from the
+	// last column to the first. Rows with fewer cells than the widest row
+	// start with empty cells, so that each column stays aligned. Options
+	// that are set per column, such as ColumnAlign, still refer to the
+	// columns by their original indexes. Only the text table is reversed;
+	// WriteCSV and the other export methods keep the original order.
+	ReverseColumns bool
+
+	// ColumnOrder, if non-nil, lists the indexes of the columns to display,
+	// in the order in which to display them. Columns that are not listed
+	// are omitted, and a listed column that a row doesn't have is empty.
+	// Options that are set per column, such as ColumnAlign, still refer to
+	// the columns by their original indexes. ReverseColumns and
+	// HideColumns apply to the columns listed. Like HideColumns, it has no
+	// effect on WriteCSV, WriteTSV, WriteMarkdown, or WriteHTML, which
+	// write every column in its original order.
+	ColumnOrder []int
+
+	// HideColumns lists the indexes of columns that are not displayed in
+	// the text table (and by Grid and Layout). The table is laid out as if
+	// the cells of those columns did not exist. Indexes beyond the end of
+	// a row are ignored. WriteCSV, WriteTSV, WriteMarkdown, and WriteHTML
+	// still write the hidden columns.
+	HideColumns []int
+
+	// CollapseColumns lists the indexes of columns in which a cell is
+	// displayed empty if its text is the same as that of the cell
+	// directly above it, so that a run of repeated values is shown only
+	// once. A rule, spanning row, or blank row (see AddBlankRow) between
+	// two rows ends the run. The widths of the columns are still those of
+	// their full contents.
+	CollapseColumns []int
+
+	// Indent is the number of pad characters written at the start of each
+	// line, to the left of the table.
+	Indent int
+
+	// CellStyle, if non-nil, is called as each line of each cell is
+	// written to the text table, with the cell's row (counting from 0, as
+	// for Buffer.SetCell, or -1 for the header and NumRows for the footer),
+	// its column (or -1 for the row number column), and the text of the
+	// line after it has been truncated or wrapped. The string it returns,
+	// such as the text wrapped in ANSI escape sequences, is written in its
+	// place and aligned according to its width. The widths of the columns
+	// are computed without CellStyle, so it shouldn't change the visible
+	// width of the text. CellStyle doesn't apply to span rows, or to the
+	// output of Grid, WriteCSV, WriteMarkdown, and so on.
+	CellStyle func(row, col int, text string) string
+
+	// RowPrefix and RowSuffix are written at the start and end of each
+	// line, after the indentation given by Indent and after any padding
+	// (so PadLastCell lines up the suffixes). They don't count toward the
+	// width of the table or of any column.
+	RowPrefix string
+	RowSuffix string
+
+	// CRLF ends each line of the text table with "\r\n" instead of "\n".
+	CRLF bool
+
+	// TrimTrailing removes the padding that would otherwise follow the
+	// last non-empty cell of a line (for example, when the row ends with
+	// empty cells). Spaces in the cells themselves are kept.
+	TrimTrailing bool
+
+	// TrimCells removes spaces and tabs from the end of the text of each
+	// cell when it is added, so that they don't count toward its width or
+	// affect its alignment. TrimCellsFull removes them from both ends.
+	TrimCells     bool
+	TrimCellsFull bool
+
+	// PadLastCell pads every line out to the full width of the table,
+	// including after the last cell of each row, so that all lines have
+	// the same visible width. The padding is omitted if TrimTrailing is
+	// also set.
+	PadLastCell bool
+
+	// ColumnSeparator is a string, such as "|", written between adjacent
+	// cells. The padding between the cells is inserted on both sides of
+	// the separator. ColumnSeparator is ignored if there is a border.
+	ColumnSeparator string
+
+	// Border selects the style of border drawn around the table and
+	// between its columns. When there is a border, Padding is inserted
+	// on both sides of each cell, inside the border.
+	Border Border
+
+	// HeaderRule is the character used to draw the line below the header
+	// set by Buffer.SetHeader (and above the footer set by
+	// Buffer.SetFooter). If HeaderRule is 0, '-' is used.
+	HeaderRule byte
+	// HeaderRuleSpansPadding makes the line below the header (and above
+	// the footer) continue through the padding between columns, rather
+	// than leaving the padding as PadChar characters.
+	HeaderRuleSpansPadding bool
+
+	// RuleChar is the character used to draw the rules added by
+	// Buffer.AddRule. If RuleChar is 0, HeaderRule (or '-') is used.
+	RuleChar byte
+	// RuleSpansPadding makes the rules added by Buffer.AddRule continue
+	// through the padding between columns, like HeaderRuleSpansPadding.
+	RuleSpansPadding bool
+
+	// TitleAlign is the alignment of the title and caption set by
+	// Buffer.SetTitle and Buffer.SetCaption within the width of the table.
+	// The default is AlignLeft.
+	TitleAlign Align
+
+	// StripCSIForCSV removes ANSI CSI and OSC sequences from cells written by
+	// WriteCSV and WriteTSV.
+	StripCSIForCSV bool
+
+	// Concurrent makes it safe to use a Buffer from multiple goroutines at
+	// once: each method holds a lock on the Buffer while it runs. The order
+	// of rows added concurrently is unspecified. (Lines holds the lock for
+	// the duration of the iteration, so the loop must not call other
+	// methods of the Buffer.)
+	Concurrent bool
+
+	// ColumnAlign sets the default alignment of each column by index.
+	// Columns beyond the end of ColumnAlign use AlignRight to pick
+	// between left and right alignment.
+	//
+	// The alignment of a cell is determined by the first of these that
+	// applies:
+	//
+	//   1. An alignment marker, such as Right, around the value
+	//   2. The Alignment method of the value, if it is an Aligner
+	//   3. The ColumnAlign entry for the cell's column
+	//   4. AlignRight
+	ColumnAlign []Align
+
+	// AutoAlignNumbers right-aligns cells whose text is a number, such as
+	// "-12", "1,234.5", "6.02e23", or "50%", unless their values have
+	// alignment markers or are Aligners. This takes precedence over
+	// ColumnAlign and AlignRight.
+	AutoAlignNumbers bool
+
+	// ColumnAutoAlign aligns each cell according to the type of its value:
+	// numbers (of any numeric type) and bools are aligned to the right,
+	// and other values, such as strings, to the left, unless the values
+	// have alignment markers or are Aligners. Unlike AutoAlignNumbers, the
+	// text of the cell isn't considered, so a string such as "12" is
+	// aligned to the left. This takes precedence over ColumnAlign and
+	// AlignRight.
+	ColumnAutoAlign bool
+
+	// ColumnMinWidth sets the minimum width of each column by index,
+	// overriding MinWidth. Columns beyond the end of ColumnMinWidth use
+	// MinWidth.
+	ColumnMinWidth []int
+
+	// StickyWidths makes each column at least as wide as it has been in
+	// any table previously written by the Buffer (with WriteTo, String,
+	// Render, Lines, and so on), so that the columns of a table that is
+	// rewritten as it changes only ever grow. Methods that don't write the
+	// table, such as Size, Grid, and Layout, use the recorded widths but
+	// don't change them. Reset does not forget the widths. Since writing
+	// the table records its widths, a Buffer with StickyWidths set must
+	// not be written from multiple goroutines at once unless Concurrent is
+	// set.
+	StickyWidths bool
+
+	// ColumnPadding sets the padding before each column by index,
+	// overriding Padding. (The first entry is unused unless there is a
+	// border or RowNumbers is set.) Columns beyond the end of
+	// ColumnPadding use Padding.
+	ColumnPadding []int
+
+	// ColumnFormat sets a fmt format string, such as "%.2f", used to
+	// format the values in each column by index. Values in columns with
+	// an empty format (or beyond the end of ColumnFormat) are formatted
+	// as with fmt.Sprint. A value that doesn't suit the format is
+	// displayed with fmt's usual error text, such as "%!d(string=x)".
+	ColumnFormat []string
+
+	// BoolGlyphs makes bool values display as TrueGlyph and FalseGlyph
+	// rather than "true" and "false".
+	BoolGlyphs bool
+	// TrueGlyph and FalseGlyph are the text shown for bool values if
+	// BoolGlyphs is set. If they are empty, "✓" and "✗" are used.
+	TrueGlyph  string
+	FalseGlyph string
+
+	// NilText, if set, is displayed for nil values instead of "<nil>".
+	// ColumnFormat is not applied to it. (Typed nil values, such as nil
+	// pointers or slices, are formatted as usual.)
+	NilText string
+
+	// GroupDigits inserts GroupSep between each group of three digits in
+	// the integer part of integer and floating-point values, such as
+	// "1,234,567.89". It applies to the text of the value after
+	// formatting, but not to strings or to values with their own String or
+	// Error methods. With a ColumnFormat, it only applies if the format's
+	// verb is %d, %f, %F, %g, %G, or %v and the format has no 0 flag, so
+	// that, for instance, hexadecimal and zero-padded numbers are not
+	// grouped.
+	GroupDigits bool
+	// GroupSep is the separator used by GroupDigits. If GroupSep is 0,
+	// ',' is used.
+	GroupSep rune
+
+	// TimeLayout, if set, is the layout used to format time.Time and
+	// *time.Time values, as with time.Time.Format. (A nil *time.Time is
+	// treated as a nil value; see NilText.)
+	TimeLayout string
+
+	// ErrorPrefix and ErrorStyle change how values that implement error
+	// are displayed: as ErrorStyle, then ErrorPrefix, then the text of the
+	// error (without ColumnFormat), then an ANSI reset sequence if
+	// ErrorStyle is set. ErrorStyle is meant to be an ANSI SGR sequence
+	// such as "\x1b[31m", which has a width of 0. If either is set, nil
+	// values, such as nil errors and errors that are nil pointers, are
+	// displayed as NilText even if it is empty.
+	ErrorPrefix string
+	ErrorStyle  string
+
+	// IndentWidth is the number of spaces per level of indentation added
+	// by Buffer.AddIndentedRow. If IndentWidth is 0, 2 is used.
+	IndentWidth int
 }
 
 // A Buffer stores rows of text and prints them as a table.
-// It assumes that each Unicode code point has a width of 1.
+// It assumes that each Unicode code point has a width of 1 (unless
+// Options.EastAsianWidth, Options.WideChars, or Options.GraphemeWidth is
+// set) and, unless
+// Options.CountCSI is set, that ANSI CSI and OSC escape sequences (such as
+// color codes and hyperlinks) have a width of 0.
 type Buffer struct {
-	opts Options
-	buf  []byte
-	rows [][]cell
+	mu     sync.Mutex // held by methods if opts.Concurrent is set
+	opts   Options
+	header []cell // nil if there is no header
+	footer []cell // nil if there is no footer
+	rows   []row
+	// title and caption are written before and after the table, if they
+	// are not empty.
+	title   string
+	caption string
+	// widths holds fixed widths for the columns, overriding the widths of
+	// their cells (see SetColumnWidths and NewStream).
+	widths []int
+	// sticky holds the widest width of each displayed column so far, if
+	// opts.StickyWidths is set.
+	sticky []int
+}
+
+type row struct {
+	cells []cell
+	kind  rowKind
+	align *Align // the alignment given to AddRowAligned, if any
 }
 
+type rowKind int
+
+const (
+	rowCells rowKind = iota // an ordinary row
+	rowSpan                 // a single cell that spans every column
+	rowRule                 // a horizontal rule
+	rowBlank                // an empty line
+)
+
 type cell struct {
-	wb    int  // width in bytes
-	wr    int  // width in runes
-	right bool // whether to right-align
+	s     string // the formatted value
+	wc    int    // visible width (see Options.cellWidth) of the widest line
+	align Align  // how to align the cell in its column
+}
+
+// An Align specifies how a cell is aligned within its column.
+type Align int
+
+// These are the possible alignments.
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+	// AlignDecimal lines up the decimal points ('.') of the cells in a
+	// column. A cell without a decimal point is aligned as if it had one
+	// at the end. Cells of the header and footer are right-aligned.
+	AlignDecimal
+	// AlignJustify widens the spaces between the words of a cell so that
+	// it fills its column. A cell without any spaces between words is
+	// aligned to the left.
+	AlignJustify
+)
+
+// A VAlign specifies where the lines of a cell are placed in a row that is
+// taller than the cell.
+type VAlign int
+
+// These are the possible vertical alignments.
+const (
+	VAlignTop VAlign = iota
+	VAlignMiddle
+	VAlignBottom
+)
+
+// An Aligner is a value that specifies its own alignment when it is passed
+// to Buffer.AddRow. An alignment marker such as Right around the value takes
+// precedence. Alignment is not called on a nil pointer.
+type Aligner interface {
+	Alignment() Align
+}
+
+// A CellStringer is a value that has its own text for display in a table.
+// Buffer.AddRow formats a CellStringer using its TableCell method in place
+// of fmt.Sprint, so that it may be shown differently than by its String
+// method. (A ColumnFormat for the column is applied to the text that
+// TableCell returns.) TableCell is not called on a nil pointer.
+type CellStringer interface {
+	TableCell() string
 }
 
 // New constructs a Buffer with options.
@@ -46,6 +515,85 @@ func New(opts Options) *Buffer {
 	return &Buffer{opts: opts}
 }
 
+// NewChecked is like New, but returns an error if opts is invalid: if a
+// numeric option such as Padding or an entry of ColumnMinWidth is negative,
+// if an option such as ColumnAlign or Border has a value that isn't one of
+// the defined constants, or if a column index such as an entry of
+// HideColumns is negative. New accepts such options and does its best with
+// them.
+func NewChecked(opts Options) (*Buffer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return New(opts), nil
+}
+
+func (o *Options) validate() error {
+	for _, f := range []struct {
+		name string
+		n    int
+	}{
+		{"MinWidth", o.MinWidth},
+		{"Padding", o.Padding},
+		{"MaxWidth", o.MaxWidth},
+		{"MaxTableWidth", o.MaxTableWidth},
+		{"WrapWidth", o.WrapWidth},
+		{"TabWidth", o.TabWidth},
+		{"Indent", o.Indent},
+		{"IndentWidth", o.IndentWidth},
+	} {
+		if f.n < 0 {
+			return fmt.Errorf("tabular: negative %s (%d)", f.name, f.n)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		ns   []int
+	}{
+		{"ColumnMinWidth", o.ColumnMinWidth},
+		{"ColumnPadding", o.ColumnPadding},
+		{"ColumnOrder", o.ColumnOrder},
+		{"HideColumns", o.HideColumns},
+		{"CollapseColumns", o.CollapseColumns},
+	} {
+		for i, n := range f.ns {
+			if n < 0 {
+				return fmt.Errorf("tabular: negative %s[%d] (%d)", f.name, i, n)
+			}
+		}
+	}
+	for i, a := range o.ColumnAlign {
+		if a < AlignLeft || a > AlignJustify {
+			return fmt.Errorf("tabular: invalid ColumnAlign[%d] (%d)", i, a)
+		}
+	}
+	if o.TitleAlign < AlignLeft || o.TitleAlign > AlignJustify {
+		return fmt.Errorf("tabular: invalid TitleAlign (%d)", o.TitleAlign)
+	}
+	if o.VerticalAlign < VAlignTop || o.VerticalAlign > VAlignBottom {
+		return fmt.Errorf("tabular: invalid VerticalAlign (%d)", o.VerticalAlign)
+	}
+	if o.WrapMode < WrapRune || o.WrapMode > WrapWord {
+		return fmt.Errorf("tabular: invalid WrapMode (%d)", o.WrapMode)
+	}
+	if o.TruncateMode < TruncateEnd || o.TruncateMode > TruncateMiddle {
+		return fmt.Errorf("tabular: invalid TruncateMode (%d)", o.TruncateMode)
+	}
+	if _, ok := borderStyles[o.Border]; !ok && o.Border != BorderNone {
+		return fmt.Errorf("tabular: invalid Border (%d)", o.Border)
+	}
+	return nil
+}
+
+// Fprint writes rows to w as a text table formatted with opts. It is
+// equivalent to adding rows to a new Buffer with AddRows and calling
+// WriteTo.
+func Fprint(w io.Writer, opts Options, rows ...[]interface{}) (int64, error) {
+	b := New(opts)
+	b.AddRows(rows...)
+	return b.WriteTo(w)
+}
+
 // Right marks a value passed to Buffer.AddRow for right alignment.
 func Right(v interface{}) interface{} {
 	return right{v}
@@ -68,85 +616,1603 @@ func (l left) String() string {
 	return fmt.Sprint(l.v)
 }
 
+// Center marks a value passed to Buffer.AddRow for center alignment.
+// If the leftover space in the column is odd, the extra space goes on the
+// right.
+func Center(v interface{}) interface{} {
+	return center{v}
+}
+
+type center struct{ v interface{} }
+
+func (c center) String() string {
+	return fmt.Sprint(c.v)
+}
+
+// Decimal marks a value passed to Buffer.AddRow for decimal-point alignment
+// (see AlignDecimal).
+func Decimal(v interface{}) interface{} {
+	return decimal{v}
+}
+
+type decimal struct{ v interface{} }
+
+func (d decimal) String() string {
+	return fmt.Sprint(d.v)
+}
+
+// Justify marks a value passed to Buffer.AddRow for justified alignment
+// (see AlignJustify).
+func Justify(v interface{}) interface{} {
+	return justify{v}
+}
+
+type justify struct{ v interface{} }
+
+func (j justify) String() string {
+	return fmt.Sprint(j.v)
+}
+
+// Cellf formats a value for Buffer.AddRow according to format, as with
+// fmt.Sprintf. The result is used as the text of the cell as is; in
+// particular, the column's Options.ColumnFormat is not applied. It may be
+// wrapped in an alignment marker such as Right.
+func Cellf(format string, args ...interface{}) interface{} {
+	return preformatted{fmt.Sprintf(format, args...)}
+}
+
+type preformatted struct{ s string }
+
+func (p preformatted) String() string {
+	return p.s
+}
+
+// Cells groups values that are passed to Buffer.AddRow (or a similar method,
+// such as SetHeader) as a single argument, so that they become separate
+// cells of the row: b.AddRow("id", Cells(parts...)) is the same as adding
+// "id" followed by each of parts. An alignment marker such as Right around
+// Cells applies to each of the values, as if each were wrapped in it.
+// Cells may be nested. In other places, such as SetCell, a Cells value is
+// a single cell formatted like a slice.
+func Cells(vs ...interface{}) interface{} {
+	return spread{vs}
+}
+
+type spread struct{ vs []interface{} }
+
+func (s spread) String() string {
+	return fmt.Sprint(s.vs)
+}
+
+// expandCells returns vs with the values grouped by Cells in place of the
+// groups. It returns vs itself if there are none.
+func expandCells(vs []interface{}) []interface{} {
+	var out []interface{}
+	for i, v := range vs {
+		// marks holds the alignment markers around v, outermost first.
+		var marks []func(interface{}) interface{}
+		u := v
+	unwrap:
+		for {
+			switch m := u.(type) {
+			case right:
+				marks, u = append(marks, Right), m.v
+			case left:
+				marks, u = append(marks, Left), m.v
+			case center:
+				marks, u = append(marks, Center), m.v
+			case decimal:
+				marks, u = append(marks, Decimal), m.v
+			case justify:
+				marks, u = append(marks, Justify), m.v
+			default:
+				break unwrap
+			}
+		}
+		s, ok := u.(spread)
+		if !ok {
+			if out != nil {
+				out = append(out, v)
+			}
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}{}, vs[:i]...)
+		}
+		for _, e := range expandCells(s.vs) {
+			for k := len(marks) - 1; k >= 0; k-- {
+				e = marks[k](e)
+			}
+			out = append(out, e)
+		}
+	}
+	if out == nil {
+		return vs
+	}
+	return out
+}
+
+// Zeropad formats an integer value passed to Buffer.AddRow with leading
+// zeros, as by fmt.Sprintf("%0*d", width, v), so that it is at least width
+// digits long (including any minus sign), such as "007". The column's
+// Options.ColumnFormat and GroupDigits are not applied. Other values are
+// formatted as usual. Zeropad combines with alignment markers such as
+// Right, in either order.
+func Zeropad(v interface{}, width int) interface{} {
+	return zeropad{v, width}
+}
+
+type zeropad struct {
+	v     interface{}
+	width int
+}
+
+func (z zeropad) String() string {
+	return fmt.Sprint(z.v)
+}
+
+// Percent formats a number passed to Buffer.AddRow as a percentage with the
+// given number of digits after the decimal point, so that 0.125 with 1
+// decimal is "12.5%". Values outside [0, 1] are formatted in the same way,
+// such as "-5.0%" or "250.0%". NaN and infinite values are written without a
+// percent sign, as "NaN", "+Inf", and "-Inf". Values that aren't numbers
+// are formatted as usual. The cell is aligned to the right unless it is
+// wrapped in an alignment marker such as Left; as with CellStringer values,
+// the column's Options.ColumnFormat is applied to the text.
+func Percent(v interface{}, decimals int) interface{} {
+	return percent{v, decimals}
+}
+
+type percent struct {
+	v        interface{}
+	decimals int
+}
+
+func (p percent) Alignment() Align { return AlignRight }
+
+func (p percent) TableCell() string {
+	var f float64
+	switch rv := reflect.ValueOf(p.v); rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(rv.Uint())
+	default:
+		return fmt.Sprint(p.v)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	decimals := p.decimals
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strconv.FormatFloat(f*100, 'f', decimals, 64) + "%"
+}
+
+func (p percent) String() string {
+	return p.TableCell()
+}
+
 // AddRow adds a row of values to the buffer.
 //
-// Each value is turned into a string using the same formatting as fmt.Sprint.
+// Each value is turned into a string using the same formatting as fmt.Sprint
+// (or fmt.Sprintf with the column's Options.ColumnFormat).
+// A value containing newlines is displayed on multiple lines; the other cells
+// of the row are left blank on the extra lines.
+// If a value is wrapped in more than one of the alignment markers (Right,
+// Left, Center, Decimal, and Justify),
+// the innermost marker determines the alignment.
+// A row without any values is written as an empty line, unless there is a
+// border (see AddBlankRow).
 func (b *Buffer) AddRow(vs ...interface{}) {
+	b.AppendRow(vs...)
+}
+
+// AppendRow adds a row of values, like AddRow, and returns its index, for
+// use with methods such as SetCell.
+func (b *Buffer) AppendRow(vs ...interface{}) int {
+	defer b.lock()()
+	cells := b.makeRow(vs)
+	b.lockWidths(cells)
+	b.rows = append(b.rows, row{cells: cells})
+	return len(b.rows) - 1
+}
+
+// lockWidths fixes the widths of the columns to those of cells, if
+// opts.LockWidthsAfterFirstRow is set and they aren't already fixed.
+func (b *Buffer) lockWidths(cells []cell) {
+	if !b.opts.LockWidthsAfterFirstRow || b.widths != nil || len(cells) == 0 {
+		return
+	}
+	b.widths = make([]int, len(cells))
+	for i, c := range cells {
+		b.widths[i] = c.wc
+		if i < len(b.header) && b.header[i].wc > c.wc {
+			b.widths[i] = b.header[i].wc
+		}
+		if min := b.columnMinWidth(i); b.widths[i] < min {
+			b.widths[i] = min
+		}
+	}
+}
+
+// AddRowAligned adds a row of values, like AddRow, in which a is the
+// alignment of every cell whose value doesn't have an alignment marker such
+// as Right. It takes precedence over the alignment of Aligner values and the
+// alignment options. Cells later replaced by SetCell keep the alignment.
+func (b *Buffer) AddRowAligned(a Align, vs ...interface{}) {
+	defer b.lock()()
+	vs = expandCells(vs)
+	cells := make([]cell, len(vs))
+	for i, v := range vs {
+		cells[i] = b.makeAlignedCell(i, v, a)
+	}
+	b.lockWidths(cells)
+	b.rows = append(b.rows, row{cells: cells, align: &a})
+}
+
+// AddIndentedRow adds a row of values, like AddRow, with the first value
+// indented by depth levels of Options.IndentWidth spaces, such as to display
+// a tree. The indentation is part of the cell and counts toward the width of
+// its column.
+func (b *Buffer) AddIndentedRow(depth int, vs ...interface{}) {
+	defer b.lock()()
+	cells := b.makeRow(vs)
+	if len(cells) > 0 && depth > 0 {
+		n := b.opts.IndentWidth
+		if n == 0 {
+			n = 2
+		} else if n < 0 {
+			n = 0
+		}
+		indent := strings.Repeat(" ", depth*n)
+		c := &cells[0]
+		c.s = indent + strings.ReplaceAll(c.s, "\n", "\n"+indent)
+		c.wc = b.measure(*c)
+	}
+	b.lockWidths(cells)
+	b.rows = append(b.rows, row{cells: cells})
+}
+
+// AddSpanRow adds a row containing a single value that spans the full width
+// of the table, such as a section title. The value is formatted and aligned
+// like a value in the first column of a row passed to AddRow, but it is not
+// considered when computing the widths of the columns. If Options.Border is
+// set, a value wider than the table is truncated to fit inside the border.
+func (b *Buffer) AddSpanRow(v interface{}) {
+	defer b.lock()()
+	b.rows = append(b.rows, row{cells: []cell{b.makeCell(0, v)}, kind: rowSpan})
+}
+
+// AddRule adds a horizontal rule across the table, drawn with
+// Options.RuleChar, such as to separate groups of rows. With a border, the
+// rule is drawn like the one below the header. A rule is counted by NumRows
+// as a row with no cells.
+func (b *Buffer) AddRule() {
+	defer b.lock()()
+	b.rows = append(b.rows, row{kind: rowRule})
+}
+
+// AddBlankRow adds an empty line to the table, such as to separate groups
+// of rows. Unlike a row added by AddRow with no values, a blank row is
+// always an empty line (apart from Options.Indent, RowPrefix, and
+// RowSuffix), even if there is a border or PadLastCell is set. A blank row
+// is counted by NumRows as a row with no cells.
+func (b *Buffer) AddBlankRow() {
+	defer b.lock()()
+	b.rows = append(b.rows, row{kind: rowBlank})
+}
+
+// InsertRow inserts a row of values, formatted as by AddRow, before the
+// row at index i (counting from 0 and not including the header). If i is
+// NumRows(), InsertRow is the same as AddRow. It panics if i is out of
+// range.
+func (b *Buffer) InsertRow(i int, vs ...interface{}) {
+	defer b.lock()()
+	if i < 0 || i > len(b.rows) {
+		panic(fmt.Sprintf("tabular: InsertRow index %d out of range [0, %d]", i, len(b.rows)))
+	}
+	b.rows = append(b.rows, row{})
+	copy(b.rows[i+1:], b.rows[i:])
+	b.rows[i] = row{cells: b.makeRow(vs)}
+	b.lockWidths(b.rows[i].cells)
+}
+
+// DeleteRow removes the row at index i (counting from 0 and not including
+// the header). It panics if i is out of range.
+func (b *Buffer) DeleteRow(i int) {
+	defer b.lock()()
+	if i < 0 || i >= len(b.rows) {
+		panic(fmt.Sprintf("tabular: DeleteRow index %d out of range [0, %d)", i, len(b.rows)))
+	}
+	copy(b.rows[i:], b.rows[i+1:])
+	b.rows[len(b.rows)-1] = row{}
+	b.rows = b.rows[:len(b.rows)-1]
+}
+
+// AddRows adds each of rows to the buffer as if by AddRow.
+func (b *Buffer) AddRows(rows ...[]interface{}) {
+	for _, vs := range rows {
+		b.AddRow(vs...)
+	}
+}
+
+// SetHeader sets a header row for the table, replacing any previous
+// header. The header is formatted in the same way as a row passed to AddRow
+// and is written before the other rows, followed by a line of
+// Options.HeaderRule characters.
+func (b *Buffer) SetHeader(vs ...interface{}) {
+	defer b.lock()()
+	b.header = b.makeRow(vs)
+}
+
+// SetTitle sets a line of text to be written above the table, aligned
+// within the width of the table according to Options.TitleAlign. The title
+// doesn't affect the widths of the columns. SetTitle("") removes the title.
+func (b *Buffer) SetTitle(s string) {
+	defer b.lock()()
+	b.title = s
+}
+
+// SetCaption sets a line of text to be written below the table, aligned in
+// the same way as the title set by SetTitle. SetCaption("") removes the
+// caption.
+func (b *Buffer) SetCaption(s string) {
+	defer b.lock()()
+	b.caption = s
+}
+
+// Reset discards all rows, including the header and footer, and the title
+// and caption, leaving b as it was when it was created by New.
+func (b *Buffer) Reset() {
+	defer b.lock()()
+	b.header = nil
+	b.footer = nil
+	b.rows = nil
+	b.title = ""
+	b.caption = ""
+}
+
+// SetColumnWidths fixes the widths of the columns of the table to the
+// corresponding entries of widths, instead of computing them from the cells.
+// The widths are used as is, without applying MinWidth or ColumnMinWidth.
+// Columns beyond the end of widths, or whose entry is negative, have their
+// widths computed as usual. Cells wider than their columns overflow them,
+// pushing the rest of the line to the right, unless
+// Options.TruncateFixedWidths is set. SetColumnWidths(nil) restores the
+// usual behavior.
+func (b *Buffer) SetColumnWidths(widths []int) {
+	defer b.lock()()
+	b.widths = append([]int(nil), widths...)
+}
+
+// Clone returns a copy of b with the same options and rows. Changes to the
+// copy, such as adding rows or calling SetCell, don't affect b, and vice
+// versa.
+func (b *Buffer) Clone() *Buffer {
+	defer b.lock()()
+	c := &Buffer{
+		opts:    b.opts,
+		header:  cloneCells(b.header),
+		footer:  cloneCells(b.footer),
+		title:   b.title,
+		caption: b.caption,
+		widths:  append([]int(nil), b.widths...),
+		sticky:  append([]int(nil), b.sticky...),
+	}
+	c.rows = make([]row, len(b.rows))
+	for i, r := range b.rows {
+		r.cells = cloneCells(r.cells)
+		c.rows[i] = r
+	}
+	return c
+}
+
+// Append adds copies of the rows of other (not including its header or
+// footer) to the end of b, including rules and span rows. The cells keep
+// the text and alignment given to them by other's options, but the table
+// is laid out according to b's options, with the widths of the columns
+// computed over the rows of both.
+func (b *Buffer) Append(other *Buffer) {
+	rows := other.Clone().rows
+	defer b.lock()()
+	for _, r := range rows {
+		for i := range r.cells {
+			r.cells[i].wc = b.measure(r.cells[i])
+		}
+		b.rows = append(b.rows, r)
+	}
+}
+
+func cloneCells(cells []cell) []cell {
+	if cells == nil {
+		return nil
+	}
+	return append([]cell{}, cells...)
+}
+
+// NumRows returns the number of rows added by AddRow, not counting the
+// header or footer.
+func (b *Buffer) NumRows() int {
+	defer b.lock()()
+	return len(b.rows)
+}
+
+// NumColumns returns the number of cells in the longest row, including the
+// header and footer but not rows added by AddSpanRow.
+func (b *Buffer) NumColumns() int {
+	defer b.lock()()
+	return b.numColumns()
+}
+
+func (b *Buffer) numColumns() int {
+	n := len(b.header)
+	if len(b.footer) > n {
+		n = len(b.footer)
+	}
+	for _, r := range b.rows {
+		if r.kind == rowCells && len(r.cells) > n {
+			n = len(r.cells)
+		}
+	}
+	return n
+}
+
+// Cell returns the formatted value of the cell in the given column of the
+// given row (counting from 0 and not including the header), or "" if there
+// is no such cell.
+func (b *Buffer) Cell(row, col int) string {
+	defer b.lock()()
+	if row < 0 || row >= len(b.rows) || col < 0 || col >= len(b.rows[row].cells) {
+		return ""
+	}
+	return b.rows[row].cells[col].s
+}
+
+// SetCell replaces the cell in the given column of the given row (counting
+// from 0 and not including the header) with v, which is formatted as by
+// AddRow. It panics if there is no such cell.
+func (b *Buffer) SetCell(row, col int, v interface{}) {
+	defer b.lock()()
+	if row < 0 || row >= len(b.rows) || col < 0 || col >= len(b.rows[row].cells) {
+		panic(fmt.Sprintf("tabular: SetCell(%d, %d) is out of range", row, col))
+	}
+	if a := b.rows[row].align; a != nil {
+		b.rows[row].cells[col] = b.makeAlignedCell(col, v, *a)
+	} else {
+		b.rows[row].cells[col] = b.makeCell(col, v)
+	}
+}
+
+// SortByColumn stably sorts the rows (not including the header) using less
+// to compare the formatted values of their cells in column col. Rows that
+// don't have a cell in column col are sorted as if the cell were empty.
+// (A row added by AddSpanRow has its value in column 0.)
+func (b *Buffer) SortByColumn(col int, less func(a, b string) bool) {
+	defer b.lock()()
+	key := func(r row) string {
+		if col < len(r.cells) {
+			return r.cells[col].s
+		}
+		return ""
+	}
+	sort.SliceStable(b.rows, func(i, j int) bool {
+		return less(key(b.rows[i]), key(b.rows[j]))
+	})
+}
+
+// SetFooter sets a footer row for the table, replacing any previous footer.
+// The footer is formatted in the same way as a row passed to AddRow and is
+// written after the other rows, preceded by a line like the one below the
+// header.
+func (b *Buffer) SetFooter(vs ...interface{}) {
+	defer b.lock()()
+	b.footer = b.makeRow(vs)
+}
+
+// AddSumFooter sets a footer, as by SetFooter, containing the sum of the
+// values in each of the given columns, right-aligned, and empty cells in the
+// other columns. The values are the numbers that the cells' formatted text
+// parses as with strconv.ParseFloat, after removing the digit group
+// separators if Options.GroupDigits is set; cells that don't parse as
+// numbers, including empty cells, are skipped. If every cell counted in a
+// column holds an integer, the sum is an int64; otherwise it is a float64,
+// rounded to the largest number of decimal places among the cells. The sum
+// is formatted like any other value in the column (including with its
+// ColumnFormat, in which case the sum is a float64 unless the format's verb
+// is one for integers, such as %d). Negative columns are ignored.
+func (b *Buffer) AddSumFooter(cols ...int) {
+	defer b.lock()()
+	var n int
+	for _, col := range cols {
+		if col+1 > n {
+			n = col + 1
+		}
+	}
+	sep := ""
+	if b.opts.GroupDigits {
+		sep = string(b.opts.GroupSep)
+		if b.opts.GroupSep == 0 {
+			sep = ","
+		}
+	}
+	vs := make([]interface{}, n)
+	for i := range vs {
+		vs[i] = ""
+	}
+	for _, col := range cols {
+		if col < 0 {
+			continue
+		}
+		var isum int64
+		var fsum float64
+		isInt := true
+		places := 0 // the most decimal places seen, or -1 for an exponent
+		for _, r := range b.rows {
+			if r.kind != rowCells || col >= len(r.cells) {
+				continue
+			}
+			s := strings.TrimSpace(r.cells[col].s)
+			if sep != "" {
+				s = strings.ReplaceAll(s, sep, "")
+			}
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				isum += i
+				fsum += float64(i)
+			} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+				fsum += f
+				isInt = false
+				if strings.ContainsAny(s, "eEpPxX") || math.IsInf(f, 0) || math.IsNaN(f) {
+					places = -1
+				} else if dot := strings.IndexByte(s, '.'); dot >= 0 && places >= 0 && len(s)-dot-1 > places {
+					places = len(s) - dot - 1
+				}
+			}
+		}
+		if isInt && col < len(b.opts.ColumnFormat) && b.opts.ColumnFormat[col] != "" {
+			// Format the sum as the kind of value the format expects.
+			_, verb := formatVerb(b.opts.ColumnFormat[col])
+			isInt = strings.IndexByte("bcdoOxXUv", verb) >= 0
+		}
+		if isInt {
+			vs[col] = Right(isum)
+		} else {
+			if places >= 0 {
+				fsum, _ = strconv.ParseFloat(strconv.FormatFloat(fsum, 'f', places, 64), 64)
+			}
+			vs[col] = Right(fsum)
+		}
+	}
+	b.footer = b.makeRow(vs)
+}
+
+func (b *Buffer) makeRow(vs []interface{}) []cell {
+	vs = expandCells(vs)
 	row := make([]cell, len(vs))
 	for i, v := range vs {
-		c := cell{right: b.opts.AlignRight}
-		if r, ok := v.(right); ok {
-			v = r.v
-			c.right = true
+		row[i] = b.makeCell(i, v)
+	}
+	return row
+}
+
+func (b *Buffer) makeCell(col int, v interface{}) cell {
+	return b.newCell(col, v, b.columnAlign(col), false)
+}
+
+// makeAlignedCell is like makeCell, but it uses a as the alignment of the
+// cell unless v has an alignment marker.
+func (b *Buffer) makeAlignedCell(col int, v interface{}, a Align) cell {
+	return b.newCell(col, v, a, true)
+}
+
+// newCell formats v as a cell in column col whose alignment is align unless
+// it is changed by an alignment marker or (if marked is false) by the value
+// itself.
+func (b *Buffer) newCell(col int, v interface{}, align Align, marked bool) cell {
+	c := cell{align: align}
+	var zeros int // the width given to Zeropad
+unwrap:
+	for {
+		switch m := v.(type) {
+		case zeropad:
+			v = m.v
+			zeros = m.width
+			continue
+		case right:
+			v = m.v
+			c.align = AlignRight
+		case left:
+			v = m.v
+			c.align = AlignLeft
+		case center:
+			v = m.v
+			c.align = AlignCenter
+		case decimal:
+			v = m.v
+			c.align = AlignDecimal
+		case justify:
+			v = m.v
+			c.align = AlignJustify
+		default:
+			break unwrap
 		}
-		if l, ok := v.(left); ok {
-			v = l.v
-			c.right = false
+		marked = true
+	}
+	if a, ok := v.(Aligner); ok && !marked && !isNilPointer(v) {
+		c.align = a.Alignment()
+		marked = true
+	}
+	if cs, ok := v.(CellStringer); ok && !isNilPointer(v) {
+		v = cs.TableCell()
+	}
+	if b.opts.ColumnAutoAlign && !marked {
+		if isNumberOrBool(v) {
+			c.align = AlignRight
+		} else {
+			c.align = AlignLeft
+		}
+	}
+	if t, ok := v.(bool); ok && b.opts.BoolGlyphs {
+		v = b.opts.boolGlyph(t)
+	}
+	if b.opts.TimeLayout != "" {
+		switch t := v.(type) {
+		case time.Time:
+			v = t.Format(b.opts.TimeLayout)
+		case *time.Time:
+			if t == nil {
+				v = nil
+			} else {
+				v = t.Format(b.opts.TimeLayout)
+			}
+		}
+	}
+	styleErrors := b.opts.ErrorPrefix != "" || b.opts.ErrorStyle != ""
+	if p, ok := v.(preformatted); ok {
+		c.s = p.s
+	} else if v == nil && (b.opts.NilText != "" || styleErrors) {
+		c.s = b.opts.NilText
+	} else if err, ok := v.(error); ok && styleErrors {
+		if isNilPointer(v) {
+			c.s = b.opts.NilText
+		} else {
+			c.s = b.opts.ErrorStyle + b.opts.ErrorPrefix + err.Error()
+			if b.opts.ErrorStyle != "" {
+				c.s += "\x1b[0m"
+			}
+		}
+	} else if zeros > 0 && isPlainInteger(v) {
+		c.s = fmt.Sprintf("%0*d", zeros, v)
+	} else {
+		if col < len(b.opts.ColumnFormat) && b.opts.ColumnFormat[col] != "" {
+			c.s = fmt.Sprintf(b.opts.ColumnFormat[col], v)
+		} else {
+			c.s = fmt.Sprint(v)
+		}
+		if b.opts.GroupDigits && isPlainNumber(v) && b.groupsFormat(col) {
+			sep := b.opts.GroupSep
+			if sep == 0 {
+				sep = ','
+			}
+			c.s = groupDigits(c.s, sep)
+		}
+	}
+	if b.opts.TrimCellsFull {
+		c.s = strings.Trim(c.s, " \t")
+	} else if b.opts.TrimCells {
+		c.s = strings.TrimRight(c.s, " \t")
+	}
+	if b.opts.NormalizeNFC {
+		c.s = norm.NFC.String(c.s)
+	}
+	if b.opts.AutoAlignNumbers && !marked && isNumber(c.s) {
+		c.align = AlignRight
+	}
+	c.wc = b.measure(c)
+	return c
+}
+
+// isNilPointer reports whether v is a nil pointer. Calling a method of v,
+// such as Error, may panic in that case.
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// isNumberOrBool reports whether v is a number (of any integer,
+// floating-point, or complex type) or a bool.
+func isNumberOrBool(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// groupsFormat reports whether GroupDigits applies to values formatted with
+// the ColumnFormat of column col: whether it has no ColumnFormat or its
+// format is a decimal one, such as %d or %.2f, without zero padding.
+func (b *Buffer) groupsFormat(col int) bool {
+	if col >= len(b.opts.ColumnFormat) || b.opts.ColumnFormat[col] == "" {
+		return true
+	}
+	flags, verb := formatVerb(b.opts.ColumnFormat[col])
+	return strings.IndexByte("dfFgGv", verb) >= 0 && strings.IndexByte(flags, '0') < 0
+}
+
+// formatVerb returns the flags and the verb of the first verb in format,
+// a format string for fmt.Sprintf, or a verb of 0 if there is none.
+func formatVerb(format string) (flags string, verb byte) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0", format[j]) >= 0 {
+			j++
+		}
+		k := j
+		for k < len(format) && strings.IndexByte("0123456789.*[]", format[k]) >= 0 {
+			k++
+		}
+		if k == len(format) {
+			return "", 0
+		}
+		if format[k] == '%' {
+			i = k
+			continue
+		}
+		return format[i+1 : j], format[k]
+	}
+	return "", 0
+}
+
+// isPlainInteger reports whether v is an integer that is formatted by fmt
+// as a plain number.
+func isPlainInteger(v interface{}) bool {
+	if !isPlainNumber(v) {
+		return false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Float32, reflect.Float64:
+		return false
+	}
+	return true
+}
+
+// isPlainNumber reports whether v is an integer or floating-point value
+// that is formatted by fmt as a plain number.
+func isPlainNumber(v interface{}) bool {
+	switch v.(type) {
+	case fmt.Stringer, fmt.Formatter, error:
+		return false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// groupDigits inserts sep between each group of three digits of the first
+// run of digits in s (following an optional sign and leading spaces).
+func groupDigits(s string, sep rune) string {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	j := i
+	for j < len(s) && '0' <= s[j] && s[j] <= '9' {
+		j++
+	}
+	if j-i <= 3 {
+		return s
+	}
+	var sb strings.Builder
+	sb.WriteString(s[:i])
+	for k := i; k < j; k++ {
+		if k > i && (j-k)%3 == 0 {
+			sb.WriteRune(sep)
+		}
+		sb.WriteByte(s[k])
+	}
+	sb.WriteString(s[j:])
+	return sb.String()
+}
+
+// numberRegexp matches decimal numbers, optionally with thousands
+// separators, an exponent, or a percent sign.
+var numberRegexp = regexp.MustCompile(`^[-+]?(\d+|\d{1,3}(,\d{3})+|(\d+|\d{1,3}(,\d{3})+)?\.\d+)([eE][-+]?\d+)?%?$`)
+
+// isNumber reports whether s, ignoring surrounding spaces and escape
+// sequences, is a number.
+func isNumber(s string) bool {
+	return numberRegexp.MatchString(strings.TrimSpace(escRegexp.ReplaceAllString(s, "")))
+}
+
+func (o *Options) boolGlyph(t bool) string {
+	if t {
+		if o.TrueGlyph != "" {
+			return o.TrueGlyph
+		}
+		return "✓"
+	}
+	if o.FalseGlyph != "" {
+		return o.FalseGlyph
+	}
+	return "✗"
+}
+
+// measure returns the width of the widest line of c.
+func (b *Buffer) measure(c cell) int {
+	var n int
+	for _, line := range b.lines(c) {
+		if w := b.opts.cellWidth(line); w > n {
+			n = w
+		}
+	}
+	return n
+}
+
+// text returns a line of text from a cell as it is displayed in a table,
+// before wrapping.
+func (b *Buffer) text(s string) string {
+	if b.opts.TabWidth > 0 {
+		s = b.opts.expandTabs(s, b.opts.TabWidth)
+	}
+	if b.opts.MaxWidth > 0 {
+		s = b.opts.truncate(s, b.opts.MaxWidth)
+	}
+	return s
+}
+
+// lines splits the text of c into the lines it occupies when rendered.
+// The text is split at newlines and then each line is transformed by text
+// and wrapped according to WrapWidth.
+func (b *Buffer) lines(c cell) []string {
+	var lines []string
+	for _, s := range strings.Split(c.s, "\n") {
+		s = b.text(s)
+		if b.opts.WrapWidth > 0 {
+			lines = append(lines, b.opts.wrap(s, b.opts.WrapWidth)...)
+		} else {
+			lines = append(lines, s)
 		}
-		s := fmt.Sprint(v)
-		c.wb = len(s)
-		c.wr = utf8.RuneCountInString(s)
-		row[i] = c
-		b.buf = append(b.buf, s...)
 	}
-	b.rows = append(b.rows, row)
+	return lines
+}
+
+func (b *Buffer) columnAlign(col int) Align {
+	if col < len(b.opts.ColumnAlign) {
+		return b.opts.ColumnAlign[col]
+	}
+	if b.opts.AlignRight {
+		return AlignRight
+	}
+	return AlignLeft
+}
+
+// lock locks b if opts.Concurrent is set, returning a function that unlocks
+// it.
+func (b *Buffer) lock() (unlock func()) {
+	if !b.opts.Concurrent {
+		return func() {}
+	}
+	b.mu.Lock()
+	return b.mu.Unlock
 }
 
 // WriteTo writes the buffered rows as a text table.
+// It does not modify b, except to record the widths of its columns if
+// Options.StickyWidths is set, so the same table may be written more than
+// once.
 func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
-	var widths []int
-	for _, row := range b.rows {
-		for i, c := range row {
-			if i < len(widths) {
-				if c.wr > widths[i] {
-					widths[i] = c.wr
+	defer b.lock()()
+	_, n, err := b.writeTo(w)
+	return n, err
+}
+
+// WriteToWidths writes the buffered rows as a text table, like WriteTo, and
+// also returns the width of each column of the table as written, not
+// including padding. The widths are those of the columns as displayed, so
+// they begin with the row number column if Options.RowNumbers is set and
+// don't include columns omitted by Options.HideColumns.
+func (b *Buffer) WriteToWidths(w io.Writer) (widths []int, n int64, err error) {
+	defer b.lock()()
+	l, n, err := b.writeTo(w)
+	return append([]int(nil), l.widths...), n, err
+}
+
+func (b *Buffer) writeTo(w io.Writer) (*layout, int64, error) {
+	l := b.writeLayout()
+	if b.opts.FitTerminal {
+		if n, ok := terminalWidth(w); ok {
+			n -= b.opts.indent() + b.opts.cellWidth(b.opts.RowPrefix) + b.opts.cellWidth(b.opts.RowSuffix)
+			l.narrow(b, n)
+		}
+	}
+	var written int64
+	err := b.render(l, func(line []byte) error {
+		n, err := w.Write(line)
+		written += int64(n)
+		return err
+	})
+	return l, written, err
+}
+
+// WriteToFunc formats the buffered rows as a text table, like WriteTo, but
+// calls fn with each line of the table in turn instead of writing to an
+// io.Writer. The line includes its trailing newline and is only valid until
+// fn returns. If fn returns an error, WriteToFunc stops and returns it.
+// WriteToFunc returns the total length of the lines.
+func (b *Buffer) WriteToFunc(fn func(lineIndex int, line []byte) error) (int64, error) {
+	defer b.lock()()
+	var i int
+	var n int64
+	err := b.render(b.writeLayout(), func(line []byte) error {
+		if err := fn(i, line); err != nil {
+			return err
+		}
+		i++
+		n += int64(len(line))
+		return nil
+	})
+	return n, err
+}
+
+// Lines returns an iterator over the lines of the text table written by
+// WriteTo, without their trailing newlines. Like WriteTo, it does not modify
+// b except to record the widths of its columns for Options.StickyWidths.
+func (b *Buffer) Lines() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		defer b.lock()()
+		b.render(b.writeLayout(), func(line []byte) error {
+			n := len(line) - 1
+			if b.opts.CRLF {
+				n--
+			}
+			if !yield(string(line[:n])) {
+				return errStopLines
+			}
+			return nil
+		})
+	}
+}
+
+var errStopLines = errors.New("stop")
+
+// render formats the buffered rows as a text table with layout l, calling
+// emit with each line, including its trailing newline, in turn. The line is
+// only valid until emit returns. If emit returns an error, render stops and
+// returns it.
+func (b *Buffer) render(l *layout, emit func(line []byte) error) error {
+	widths, pads := l.widths, l.pads
+	var maxPad int
+	for i := range widths {
+		if widths[i] > maxPad {
+			maxPad = widths[i]
+		}
+		if pads[i] > maxPad {
+			maxPad = pads[i]
+		}
+	}
+	pad := newPadder(&b.opts, b.opts.padRune(), maxPad)
+	padders := make([]*padder, len(widths))
+	for i, r := range l.padRunes {
+		if i > 0 && r == l.padRunes[i-1] {
+			padders[i] = padders[i-1]
+		} else {
+			padders[i] = newPadder(&b.opts, r, maxPad)
+		}
+	}
+	border := borderStyles[b.opts.Border]
+	sep := b.opts.ColumnSeparator
+	if border != nil {
+		sep = ""
+	}
+
+	line := pad.appendPad(nil, b.opts.Indent)
+	line = append(line, b.opts.RowPrefix...)
+	indent := len(line)
+	appendPad := func(p *padder, n int) {
+		line = p.appendPad(line, n)
+	}
+	var nlines int
+	writeLine := func() error {
+		line = append(line, b.opts.RowSuffix...)
+		if b.opts.CRLF {
+			line = append(line, '\r')
+		}
+		line = append(line, '\n')
+		err := emit(line)
+		line = line[:indent]
+		nlines++
+		return err
+	}
+	// startRow and place record the positions of cells, if
+	// l.recordPositions is set. startRow begins a row of n cells, and
+	// place records that the text of cell j is about to be appended to
+	// line.
+	startRow := func(n int) {
+		if l.recordPositions {
+			row := make([]CellPosition, n)
+			for j := range row {
+				row[j] = CellPosition{Line: nlines, Start: -1, Height: 1}
+			}
+			l.positions = append(l.positions, row)
+		}
+	}
+	place := func(j int, text string) {
+		if !l.recordPositions {
+			return
+		}
+		p := &l.positions[len(l.positions)-1][j]
+		start, width := b.opts.cellWidth(string(line)), b.opts.cellWidth(text)
+		switch {
+		case p.Start < 0 || p.Width == 0 && width > 0:
+			p.Line, p.Start, p.Width, p.Height = nlines, start, width, 1
+		case width > 0:
+			p.Height = nlines - p.Line + 1
+			end := p.Start + p.Width
+			if start+width > end {
+				end = start + width
+			}
+			if start < p.Start {
+				p.Start = start
+			}
+			p.Width = end - p.Start
+		}
+	}
+	var lines [][]string
+	var gaps []int
+	var stripes []*padder
+	if b.opts.StripeChar != 0 {
+		p := newPadder(&b.opts, rune(b.opts.StripeChar), maxPad)
+		stripes = make([]*padder, len(widths))
+		for i := range stripes {
+			stripes[i] = p
+		}
+	}
+	// writeRow writes row, which is row i of the Buffer (or the header, if
+	// i is -1, or the footer, if i is len(l.rows)).
+	writeRow := func(row []cell, i int, padders []*padder) error {
+		startRow(len(row))
+		lines = lines[:0]
+		height := 1
+		for j, c := range row {
+			ls := l.cellLines(b, c, j)
+			if len(ls) > height {
+				height = len(ls)
+			}
+			lines = append(lines, ls)
+		}
+		// gaps holds the number of blank lines added above each cell by
+		// VerticalAlign.
+		gaps = gaps[:0]
+		for j, ls := range lines {
+			gap := height - len(ls)
+			if b.opts.VerticalAlign == VAlignTop || gap <= 0 {
+				gap = 0
+			} else if b.opts.VerticalAlign == VAlignMiddle {
+				gap /= 2
+			}
+			if gap > 0 {
+				lines[j] = append(make([]string, gap, gap+len(ls)), ls...)
+			}
+			gaps = append(gaps, gap)
+		}
+		for k := 0; k < height; k++ {
+			// Without a border, the first line of a row contains every
+			// cell, except for cells moved down by VerticalAlign at the
+			// end, but later lines stop after the last cell that has text
+			// on them. With a border (or PadLastCell), every line has a
+			// cell for every column.
+			end := len(row)
+			if border != nil || b.opts.PadLastCell {
+				end = len(widths)
+			} else if k > 0 {
+				for end > 0 && (len(lines[end-1]) <= k || lines[end-1][k] == "") {
+					end--
 				}
 			} else {
-				widths = append(widths, c.wr)
+				for end > 0 && gaps[end-1] > 0 {
+					end--
+				}
+			}
+			if border != nil {
+				line = append(line, border.v...)
+			}
+			// contentEnd is the end of the last cell text or border in
+			// line; anything following it is padding.
+			contentEnd := len(line)
+			for j := 0; j < end; j++ {
+				var text string
+				var styled bool
+				align := AlignLeft
+				if j < len(row) {
+					if k < len(lines[j]) {
+						text = lines[j][k]
+						styled = b.opts.CellStyle != nil
+					}
+					align = row[j].align
+				}
+				if align == AlignJustify {
+					text = b.opts.justify(text, widths[j])
+				}
+				if styled {
+					text = b.opts.CellStyle(i, l.cols[j], text)
+				}
+				if border != nil || j > 0 {
+					appendPad(padders[j], pads[j])
+				}
+				if border == nil && j > 0 && sep != "" {
+					line = append(line, sep...)
+					contentEnd = len(line)
+					appendPad(padders[j], pads[j])
+				}
+				var lpad, rpad int
+				if align == AlignDecimal {
+					lpad, rpad = l.decimalPadding(&b.opts, j, text)
+				} else {
+					lpad, rpad = alignPadding(widths[j]-b.opts.cellWidth(text), align)
+				}
+				appendPad(padders[j], lpad)
+				if j < len(row) {
+					place(j, text)
+				}
+				line = append(line, text...)
+				if text != "" {
+					contentEnd = len(line)
+				}
+				if border != nil {
+					appendPad(padders[j], rpad)
+					appendPad(padders[j], pads[j])
+					line = append(line, border.v...)
+					contentEnd = len(line)
+				} else if j < end-1 || b.opts.PadLastCell {
+					appendPad(padders[j], rpad)
+				}
+			}
+			if b.opts.TrimTrailing {
+				line = line[:contentEnd]
+			}
+			if err := writeLine(); err != nil {
+				return err
 			}
 		}
+		return nil
 	}
-	for i, n := range widths {
-		if n < b.opts.MinWidth {
-			widths[i] = b.opts.MinWidth
+	spanWidth := l.spanWidth(&b.opts)
+	writeSpan := func(c cell) error {
+		startRow(1)
+		for _, text := range b.lines(c) {
+			if border != nil {
+				line = append(line, border.v...)
+				appendPad(pad, pads[0])
+				text = b.opts.truncate(text, spanWidth)
+			}
+			if c.align == AlignJustify {
+				text = b.opts.justify(text, spanWidth)
+			}
+			lpad, rpad := alignPadding(spanWidth-b.opts.cellWidth(text), c.align)
+			appendPad(pad, lpad)
+			place(0, text)
+			line = append(line, text...)
+			if border != nil {
+				appendPad(pad, rpad)
+				appendPad(pad, pads[len(pads)-1])
+				line = append(line, border.v...)
+			} else if b.opts.PadLastCell {
+				appendPad(pad, rpad)
+			}
+			if err := writeLine(); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
-	var maxPad int
-	for _, n := range widths {
-		if n > maxPad {
-			maxPad = n
+	tableWidth := l.tableWidth(&b.opts)
+	writeTitle := func(text string) error {
+		if text == "" {
+			return nil
+		}
+		lpad, rpad := alignPadding(tableWidth-b.opts.cellWidth(text), b.opts.TitleAlign)
+		appendPad(pad, lpad)
+		line = append(line, text...)
+		if b.opts.PadLastCell {
+			appendPad(pad, rpad)
 		}
+		return writeLine()
+	}
+
+	if err := writeTitle(b.title); err != nil {
+		return err
 	}
-	if b.opts.Padding > maxPad {
-		maxPad = b.opts.Padding
+	if len(widths) == 0 {
+		// Every row is empty, except perhaps for span rows. There are no
+		// columns to draw a border around, so span rows are written as
+		// plain text.
+		border = nil
+		for _, r := range l.rows {
+			var err error
+			switch r.kind {
+			case rowSpan:
+				err = writeSpan(r.cells[0])
+			case rowCells:
+				startRow(0)
+				err = writeLine()
+			default:
+				err = writeLine()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return writeTitle(b.caption)
+	}
+	if border != nil {
+		line = border.top.appendRule(line, widths, pads)
+		if err := writeLine(); err != nil {
+			return err
+		}
+	}
+	writeSeparator := func() error {
+		if border != nil {
+			line = border.middle.appendRule(line, widths, pads)
+		} else {
+			line = b.appendSeparator(line, widths, pads, padders, b.headerRule(), b.opts.HeaderRuleSpansPadding)
+		}
+		return writeLine()
 	}
-	padBuf := strings.Repeat(string(b.opts.PadChar), maxPad)
+	writeRule := func() error {
+		if border != nil {
+			line = border.middle.appendRule(line, widths, pads)
+		} else {
+			rule := b.opts.RuleChar
+			if rule == 0 {
+				rule = b.headerRule()
+			}
+			line = b.appendSeparator(line, widths, pads, padders, rule, b.opts.RuleSpansPadding)
+		}
+		return writeLine()
+	}
+	if l.header != nil {
+		if err := writeRow(l.header, -1, padders); err != nil {
+			return err
+		}
+		if err := writeSeparator(); err != nil {
+			return err
+		}
+	}
+	var n int // ordinary rows written
+	for i, r := range l.rows {
+		var err error
+		switch r.kind {
+		case rowCells:
+			if n%2 == 1 && stripes != nil {
+				err = writeRow(r.cells, i, stripes)
+			} else {
+				err = writeRow(r.cells, i, padders)
+			}
+			n++
+		case rowSpan:
+			err = writeSpan(r.cells[0])
+		case rowRule:
+			err = writeRule()
+		case rowBlank:
+			err = writeLine()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if l.footer != nil {
+		if err := writeSeparator(); err != nil {
+			return err
+		}
+		if err := writeRow(l.footer, len(l.rows), padders); err != nil {
+			return err
+		}
+	}
+	if border != nil {
+		line = border.bottom.appendRule(line, widths, pads)
+		if err := writeLine(); err != nil {
+			return err
+		}
+	}
+	return writeTitle(b.caption)
+}
 
-	var i int
+func (o *Options) padRune() rune {
+	if o.PadRune != 0 {
+		return o.PadRune
+	}
+	return rune(o.PadChar)
+}
+
+// A padder appends padding made of a repeated character.
+type padder struct {
+	buf   string // copies of the pad character
+	size  int    // length of the pad character in bytes
+	width int    // visible width of the pad character
+}
+
+// newPadder returns a padder using the pad character r which is suitable
+// for padding about n columns at a time.
+func newPadder(o *Options, r rune, n int) *padder {
+	ch := string(r)
+	p := &padder{size: len(ch), width: o.cellWidth(ch)}
+	if p.width < 1 {
+		p.width = 1
+	}
+	p.buf = strings.Repeat(ch, n/p.width+1)
+	return p
+}
+
+// indent returns the width of o.Indent, treating a negative Indent as 0.
+func (o *Options) indent() int {
+	if o.Indent < 0 {
+		return 0
+	}
+	return o.Indent
+}
+
+// appendPad appends n columns of padding to line, or none if n is negative.
+func (p *padder) appendPad(line []byte, n int) []byte {
+	if n <= 0 {
+		return line
+	}
+	k := n / p.width
+	for k*p.size > len(p.buf) {
+		line = append(line, p.buf...)
+		k -= len(p.buf) / p.size
+	}
+	line = append(line, p.buf[:k*p.size]...)
+	for i := 0; i < n%p.width; i++ {
+		line = append(line, ' ')
+	}
+	return line
+}
+
+// alignPadding returns the padding to add to the left and right of a cell
+// with the given alignment whose text is gap characters narrower than its
+// column. (A negative gap, for text that overflows, means no padding.)
+func alignPadding(gap int, a Align) (left, right int) {
+	if gap < 0 {
+		gap = 0
+	}
+	switch a {
+	case AlignRight, AlignDecimal:
+		return gap, 0
+	case AlignCenter:
+		return gap / 2, gap - gap/2
+	default:
+		return 0, gap
+	}
+}
+
+// A CellPosition is the position of a cell in the text table written by
+// WriteTo, as reported by Buffer.Layout. Positions are measured in visible
+// columns and lines, counting from 0.
+type CellPosition struct {
+	Line   int // the first line of the cell's text
+	Start  int // the column of the start of the cell's text
+	Width  int // the width of the cell's text
+	Height int // the number of lines of the cell's text
+}
+
+// Layout returns the positions of the cells of the table written by
+// WriteTo, without writing it. Like Grid, it returns a slice for each row,
+// starting with the header and ending with the footer if they are set, with
+// an entry for each cell of the row. A row added by AddSpanRow has one
+// entry, and rules and blank rows are omitted. The position of a cell is
+// that of its text, after the indentation, RowPrefix, borders, padding, and
+// the padding that aligns the text. For a cell that takes more than one
+// line, it is the smallest rectangle containing every line. An empty cell
+// has a width of 0 and the position at which its text would be on the
+// first line of its row.
+func (b *Buffer) Layout() [][]CellPosition {
+	defer b.lock()()
+	l := b.layout()
+	l.recordPositions = true
+	b.render(l, func([]byte) error { return nil })
+	return l.positions
+}
+
+// Bytes returns the buffered rows formatted as a text table, exactly as
+// written by WriteTo.
+func (b *Buffer) Bytes() []byte {
+	var buf bytes.Buffer
+	b.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// String returns the buffered rows formatted as a text table, exactly as
+// written by WriteTo.
+func (b *Buffer) String() string {
+	return string(b.Bytes())
+}
+
+// Render returns the buffered rows formatted as a text table, like String.
+// If the table can't be narrowed to fit Options.MaxTableWidth, because the
+// minimum widths of its columns and the padding between them add up to
+// more than that, Render returns the table anyway, along with an error.
+func (b *Buffer) Render() (string, error) {
+	defer b.lock()()
+	l := b.writeLayout()
+	var buf bytes.Buffer
+	b.render(l, func(line []byte) error {
+		buf.Write(line)
+		return nil
+	})
+	if max := b.opts.MaxTableWidth; max > 0 {
+		if w := l.tableWidth(&b.opts); w > max {
+			return buf.String(), fmt.Errorf("tabular: table is %d columns wide, which exceeds MaxTableWidth (%d)", w, max)
+		}
+	}
+	return buf.String(), nil
+}
+
+// Size returns the width and height of the table written by WriteTo,
+// without writing it. The width is that of the rules across the table,
+// which is the width of the longest line unless a span row is wider than the
+// columns or the title or caption is wider than the table, plus the width
+// of Indent, RowPrefix, and RowSuffix. The height is the number of lines.
+func (b *Buffer) Size() (width, height int) {
+	defer b.lock()()
+	l := b.layout()
+	if b.title != "" {
+		height++
+	}
+	if b.caption != "" {
+		height++
+	}
+	rowHeight := func(row []cell) int {
+		n := 1
+		for _, c := range row {
+			if k := len(b.lines(c)); k > n {
+				n = k
+			}
+		}
+		return n
+	}
+	for _, r := range l.rows {
+		switch r.kind {
+		case rowCells:
+			height += rowHeight(r.cells)
+		case rowSpan:
+			height += len(b.lines(r.cells[0]))
+		case rowRule, rowBlank:
+			height++
+		}
+	}
+	if len(l.widths) == 0 {
+		return 0, height
+	}
+	if l.header != nil {
+		height += rowHeight(l.header) + 1
+	}
+	if l.footer != nil {
+		height += rowHeight(l.footer) + 1
+	}
+	if b.opts.Border != BorderNone {
+		height += 2
+	}
+	width = b.opts.indent() + l.tableWidth(&b.opts)
+	width += b.opts.cellWidth(b.opts.RowPrefix) + b.opts.cellWidth(b.opts.RowSuffix)
+	return width, height
+}
+
+// Grid returns the cells of the table as they are written by WriteTo,
+// padded to the widths of their columns and aligned, but without the
+// padding between columns, separators, or borders. Each slice holds the
+// cells of one row, starting with the header and ending with the footer if
+// they are set, and only has as many cells as the row. A row added by
+// AddSpanRow has one cell as wide as all the columns and the padding
+// between them, and rules added by AddRule and blank rows added by
+// AddBlankRow are omitted. The lines of a cell that takes more than one
+// line are separated by newlines.
+func (b *Buffer) Grid() [][]string {
+	defer b.lock()()
+	l := b.layout()
+	padders := make([]*padder, len(l.widths))
+	for i, r := range l.padRunes {
+		padders[i] = newPadder(&b.opts, r, l.widths[i])
+	}
+	// format pads and aligns the lines of c, a cell in displayed column j
+	// (or, if j is -1, a span row).
 	var line []byte
-	var written int64
-	for _, row := range b.rows {
-		line = line[:0]
-		for j, c := range row {
-			if j > 0 {
-				line = append(line, padBuf[:b.opts.Padding]...)
+	format := func(c cell, j, width int, p *padder) string {
+		var buf []byte
+		lines := b.lines(c)
+		if j >= 0 {
+			lines = l.cellLines(b, c, j)
+		}
+		for k, text := range lines {
+			if k > 0 {
+				buf = append(buf, '\n')
 			}
-			width := widths[j]
-			if c.right {
-				line = append(line, padBuf[:width-c.wr]...)
+			var lpad, rpad int
+			if c.align == AlignJustify {
+				text = b.opts.justify(text, width)
 			}
-			line = append(line, b.buf[i:i+c.wb]...)
-			i += c.wb
-			if !c.right && j < len(row)-1 {
-				line = append(line, padBuf[:width-c.wr]...)
+			if c.align == AlignDecimal && j >= 0 {
+				lpad, rpad = l.decimalPadding(&b.opts, j, text)
+			} else {
+				lpad, rpad = alignPadding(width-b.opts.cellWidth(text), c.align)
 			}
+			line = p.appendPad(line[:0], lpad)
+			line = append(line, text...)
+			line = p.appendPad(line, rpad)
+			buf = append(buf, line...)
 		}
-		line = append(line, '\n')
-		n, err := w.Write(line)
-		written += int64(n)
-		if err != nil {
-			return written, err
+		return string(buf)
+	}
+	var spanWidth int
+	for i, w := range l.widths {
+		spanWidth += w
+		if i > 0 {
+			spanWidth += l.pads[i]
+		}
+	}
+	pad := newPadder(&b.opts, b.opts.padRune(), spanWidth)
+	var grid [][]string
+	appendRow := func(row []cell) {
+		cells := make([]string, len(row))
+		for j, c := range row {
+			cells[j] = format(c, j, l.widths[j], padders[j])
+		}
+		grid = append(grid, cells)
+	}
+	if l.header != nil {
+		appendRow(l.header)
+	}
+	for _, r := range l.rows {
+		switch r.kind {
+		case rowCells:
+			appendRow(r.cells)
+		case rowSpan:
+			grid = append(grid, []string{format(r.cells[0], -1, spanWidth, pad)})
+		}
+	}
+	if l.footer != nil {
+		appendRow(l.footer)
+	}
+	return grid
+}
+
+func (b *Buffer) headerRule() byte {
+	if b.opts.HeaderRule != 0 {
+		return b.opts.HeaderRule
+	}
+	return '-'
+}
+
+// appendSeparator appends to line a rule of rule characters across a table
+// without a border, such as the one that separates the header and footer
+// from the other rows. If spans is set, the rule continues through the
+// padding between columns.
+func (b *Buffer) appendSeparator(line []byte, widths, pads []int, padders []*padder, rule byte, spans bool) []byte {
+	for i, w := range widths {
+		if i > 0 {
+			n := pads[i]
+			if sep := b.opts.ColumnSeparator; sep != "" {
+				n += b.opts.cellWidth(sep) + pads[i]
+			}
+			if spans {
+				line = append(line, strings.Repeat(string(rule), n)...)
+			} else {
+				line = padders[i].appendPad(line, n)
+			}
 		}
+		line = append(line, strings.Repeat(string(rule), w)...)
 	}
-	return written, nil
+	return line
 }
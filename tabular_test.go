@@ -2,8 +2,15 @@ package tabular
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -58,6 +65,49 @@ this..is........a.test
 `)
 }
 
+func TestColumnMinWidth(t *testing.T) {
+	b := New(Options{
+		MinWidth:       3,
+		ColumnMinWidth: []int{6, 0},
+		Padding:        1,
+		PadChar:        '.',
+	})
+	b.AddRow("a", "b", "c", "d")
+	b.AddRow(Right("e"), Right("f"))
+	b.AddRow("g")
+	testOutput(t, b, `
+a......b.c...d
+.....e.f
+g
+`)
+}
+
+func TestColumnPadding(t *testing.T) {
+	b := New(Options{
+		Padding:       1,
+		ColumnPadding: []int{0, 4, 0},
+		PadChar:       '.',
+	})
+	b.AddRow("a", Right("b"), Right("c"), "d", "e")
+	b.AddRow("fg", Right("hij"), Right("kl"), "m")
+	testOutput(t, b, `
+a.......b.c.d.e
+fg....hijkl.m
+`)
+}
+
+func TestColumnPaddingBorder(t *testing.T) {
+	b := New(Options{ColumnPadding: []int{0, 2}, PadChar: ' ', Border: BorderASCII})
+	b.AddRow("a", Right("b"))
+	b.AddRow("cd", Right("efg"))
+	testOutput(t, b, `
++--+-------+
+|a |    b  |
+|cd|  efg  |
++--+-------+
+`)
+}
+
 func TestMismatchedRows(t *testing.T) {
 	b := New(Options{MinWidth: 3, Padding: 2, PadChar: '.'})
 	b.AddRow("this", "is", Right("a"), "test")
@@ -82,6 +132,48 @@ this.........a..test
 `)
 }
 
+func TestWriteToEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := New(Options{}).WriteTo(&buf)
+	if n != 0 || err != nil || buf.Len() != 0 {
+		t.Errorf("WriteTo for empty buffer: got (%d, %v) and %q; want (0, nil) and no output", n, err, buf.String())
+	}
+
+	for _, opts := range []Options{
+		{},
+		{Padding: 3, PadChar: '.'},
+		{Padding: 1, Border: BorderASCII},
+	} {
+		b := New(opts)
+		b.AddRow("")
+		b.AddRow()
+		buf.Reset()
+		n, err := b.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != buf.Len() {
+			t.Errorf("WriteTo returned %d; wrote %d bytes", n, buf.Len())
+		}
+	}
+	b := New(Options{Padding: 2, PadChar: '.'})
+	b.AddRow("")
+	if got, want := b.String(), "\n"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestLargePadding(t *testing.T) {
+	b := New(Options{MinWidth: 12, Padding: 40, PadChar: '.'})
+	b.AddRow("a", Right("b"), Center("c"))
+	b.AddSpanRow(Right("x"))
+	want := "a" + strings.Repeat(".", 11+40+11) + "b" + strings.Repeat(".", 40+5) + "c\n" +
+		strings.Repeat(".", 12*3+40*2-1) + "x\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
 func TestDoubleRight(t *testing.T) {
 	b := New(Options{Padding: 2, PadChar: ' '})
 	b.AddRow("this", "is", Right(Right("a")), "test")
@@ -92,22 +184,2514 @@ this  is     a  test
 `)
 }
 
-func TestAddRowAfterWrite(t *testing.T) {
+func TestCenter(t *testing.T) {
 	b := New(Options{Padding: 2, PadChar: '.'})
-	b.AddRow("this", "is", Right("a"), "test")
-	b.AddRow(1, 2, Right(true), false)
+	b.AddRow("abcd", "abcdef", "x")
+	b.AddRow(Center("ab"), Center("abc"), "y")
+	b.AddRow(Center("a"), Center("a"), "z")
 	testOutput(t, b, `
-this..is.....a..test
-1.....2...true..false
+abcd..abcdef..x
+.ab....abc....y
+.a......a.....z
 `)
-	b.AddRow(2, Right("x"), 1839834, "yes")
+}
+
+func TestCenterMultiByte(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("liberté", "x")
+	b.AddRow(Center("☃"), "y")
+	b.AddRow(Center("égal"), "z")
 	testOutput(t, b, `
-this..is........a..test
-1.....2......true..false
-2......x..1839834..yes
+liberté.x
+...☃....y
+.égal...z
+`)
+}
+
+func TestCenterAlignRight(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', AlignRight: true})
+	b.AddRow("abcde", "abcde")
+	b.AddRow(Center("a"), "b")
+	b.AddRow(Center(Right("c")), Right(Center("d")))
+	testOutput(t, b, `
+abcde.abcde
+..a.......b
+....c...d
+`)
+}
+
+type money int
+
+func (m money) String() string   { return fmt.Sprintf("$%d.%02d", m/100, m%100) }
+func (m money) Alignment() Align { return AlignRight }
+
+func TestAligner(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', ColumnAlign: []Align{AlignLeft, AlignLeft}})
+	b.AddRow("item", "price")
+	b.AddRow("apple", money(150))
+	b.AddRow("car", money(1234567))
+	b.AddRow("pear", Left(money(99)))
+	b.AddRow("plum", Center(money(5)))
+	b.AddRow("fig", (*money)(nil))
+	testOutput(t, b, `
+item..price
+apple.....$1.50
+car...$12345.67
+pear..$0.99
+plum....$0.05
+fig...<nil>
+`)
+}
+
+type user struct {
+	name, email string
+}
+
+func (u user) String() string    { return fmt.Sprintf("%s <%s>", u.name, u.email) }
+func (u user) TableCell() string { return u.name }
+
+func TestCellStringer(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', ColumnFormat: []string{"", "[%s]"}})
+	b.AddRow(user{"ann", "ann@example.com"}, user{"bob", "bob@example.com"})
+	b.AddRow(Right(user{"carol", "carol@example.com"}), "x")
+	b.AddRow(fmt.Sprint(user{"dan", "dan@example.com"}))
+	b.AddRow((*user)(nil), "y")
+	testOutput(t, b, `
+ann...................[bob]
+................carol.[x]
+dan <dan@example.com>
+<nil>.................[y]
+`)
+}
+
+func TestColumnAutoAlign(t *testing.T) {
+	b := New(Options{
+		Padding:         1,
+		PadChar:         '.',
+		AlignRight:      true,
+		ColumnAutoAlign: true,
+	})
+	b.AddRow("name", "count", "price", "ok", "code")
+	b.AddRow("apple", 1, 0.5, true, "12")
+	b.AddRow("banana", int64(-22), float32(12.25), false, Right("7"))
+	b.AddRow(Center("kiwi"), uint8(3), 100.0, nil, money(150))
+	testOutput(t, b, `
+name...count.price.ok....code
+apple......1...0.5..true.12
+banana...-22.12.25.false.....7
+.kiwi......3...100.<nil>.$1.50
+`)
+}
+
+func TestIsNumber(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want bool
+	}{
+		{"0", true},
+		{"-12", true},
+		{"+3.5", true},
+		{".5", true},
+		{"1,234", true},
+		{"1,234,567.89", true},
+		{"6.02e23", true},
+		{"50%", true},
+		{" 7 ", true},
+		{"\x1b[31m-1\x1b[0m", true},
+		{"", false},
+		{"-", false},
+		{".", false},
+		{"e5", false},
+		{"1,23", false},
+		{"12a", false},
+		{"NaN", false},
+		{"v1.2", false},
+		{"1.2.3", false},
+	} {
+		if got := isNumber(tt.s); got != tt.want {
+			t.Errorf("isNumber(%q) = %t; want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestAutoAlignNumbers(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', AutoAlignNumbers: true})
+	b.AddRow("name", "value")
+	b.AddRow("a", 12)
+	b.AddRow("b", "-1,234.5")
+	b.AddRow("c", "n/a")
+	b.AddRow("d", "99%")
+	b.AddRow("e", Left(3))
+	b.AddRow("f", money(250))
+	testOutput(t, b, `
+name.value
+a..........12
+b....-1,234.5
+c....n/a
+d.........99%
+e....3
+f.......$2.50
+`)
+}
+
+func TestAddRowAligned(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', AutoAlignNumbers: true})
+	b.AddRow("name", "count", "price")
+	b.AddRow("apple", 1, money(150))
+	b.AddRow("banana", 22, money(99))
+	b.AddRowAligned(AlignRight, "total", "23", Left("$2.49"))
+	b.AddRowAligned(AlignLeft, "avg", 11.5, money(125))
+	b.SetCell(4, 1, 11.25)
+	testOutput(t, b, `
+name...count.price
+apple......1.$1.50
+banana....22.$0.99
+.total....23.$2.49
+avg....11.25.$1.25
+`)
+}
+
+func TestColumnAlign(t *testing.T) {
+	b := New(Options{
+		Padding:     1,
+		PadChar:     '.',
+		ColumnAlign: []Align{AlignLeft, AlignRight, AlignCenter},
+	})
+	b.AddRow("name", "count", "state", "note")
+	b.AddRow("a", 1, "on", "x")
+	b.AddRow("bb", Left(22))
+	b.AddRow(Right("c"))
+	b.AddRow("d", 3, Left("off"), "yy")
+	testOutput(t, b, `
+name.count.state.note
+a........1..on...x
+bb...22
+...c
+d........3.off...yy
+`)
+}
+
+func TestColumnAlignFallback(t *testing.T) {
+	b := New(Options{
+		Padding:     1,
+		PadChar:     '.',
+		AlignRight:  true,
+		ColumnAlign: []Align{AlignLeft},
+	})
+	b.AddRow("name", "count", "total")
+	b.AddRow("a", 1, Left(2))
+	b.AddRow("bb", 22)
+	testOutput(t, b, `
+name.count.total
+a........1.2
+bb......22
+`)
+}
+
+func TestDecimal(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow(Decimal("12.5"), "a")
+	b.AddRow(Decimal(3.14159), "b")
+	b.AddRow(Decimal(100), "c")
+	b.AddRow(Decimal(-7.25), "d")
+	b.AddRow(Decimal(100))
+	testOutput(t, b, `
+.12.5.....a
+..3.14159.b
+100.......c
+.-7.25....d
+100
+`)
+}
+
+func TestDecimalNarrowed(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', MaxTableWidth: 8})
+	b.AddRow(Decimal("3.14159"), "|")
+	b.AddRow(Decimal(12.5), "|")
+	testOutput(t, b, `
+3.141….|
+12.5...|
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', TruncateFixedWidths: true})
+	b.SetColumnWidths([]int{5})
+	b.AddRow(Decimal("3.14159"), "|")
+	b.AddRow(Decimal(12.5), "|")
+	testOutput(t, b, `
+3.14….|
+12.5..|
+`)
+}
+
+func TestMaxTableWidthRowNumbers(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', RowNumbers: true, MaxTableWidth: 4})
+	for i := 0; i < 12; i++ {
+		b.AddRow("abc")
+	}
+	lines := strings.Split(b.String(), "\n")
+	if got, want := lines[9], "10.…"; got != want {
+		t.Errorf("line 9: got %q; want %q", got, want)
+	}
+	if got, want := lines[0], ".1.…"; got != want {
+		t.Errorf("line 0: got %q; want %q", got, want)
+	}
+}
+
+func TestColumnAlignDecimal(t *testing.T) {
+	b := New(Options{
+		Padding:     1,
+		PadChar:     '.',
+		ColumnAlign: []Align{AlignLeft, AlignDecimal},
+	})
+	b.SetHeader("name", Right("amount (USD)"))
+	b.AddRow("a", "12.5")
+	b.AddRow("b", "3.14159")
+	b.AddRow("c", Right("n/a"))
+	b.AddRow("d", "-100")
+	testOutput(t, b, `
+name.amount (USD)
+----.------------
+a........12.5
+b.........3.14159
+c.............n/a
+d......-100
+`)
+
+	b = New(Options{
+		Padding:     1,
+		PadChar:     '.',
+		ColumnAlign: []Align{AlignLeft, AlignDecimal},
+	})
+	b.SetHeader("item", "amount")
+	b.AddRow("a", "12.5")
+	b.AddRow("b", "3.25")
+	b.SetFooter("sum", "15.75")
+	testOutput(t, b, `
+item.amount
+----.------
+a.....12.5
+b......3.25
+----.------
+sum...15.75
+`)
+}
+
+func TestBoolGlyphs(t *testing.T) {
+	rows := [][]interface{}{
+		{"a", true, Right(false)},
+		{"bbbbbb", false, Right(true)},
+	}
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+a......true..false
+bbbbbb.false..true
+`)
+	b = New(Options{Padding: 1, PadChar: '.', BoolGlyphs: true})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+a......✓.✗
+bbbbbb.✗.✓
+`)
+	b = New(Options{Padding: 1, PadChar: '.', BoolGlyphs: true, TrueGlyph: "yes"})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+a......yes...✗
+bbbbbb.✗...yes
+`)
+}
+
+func TestNilText(t *testing.T) {
+	var p *int
+	var err error
+	rows := [][]interface{}{
+		{nil, 1, err},
+		{"b", Right(nil), p},
+		{"c", 22, []int(nil)},
+	}
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+<nil>.1.....<nil>
+b.....<nil>.<nil>
+c.....22....[]
+`)
+	b = New(Options{Padding: 1, PadChar: '.', NilText: "-", ColumnFormat: []string{"", "%03d"}})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+-.001.-
+b...-.<nil>
+c.022.[]
+`)
+}
+
+func TestGroupDigits(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want string
+	}{
+		{"0", "0"},
+		{"123", "123"},
+		{"1234", "1,234"},
+		{"-1234567", "-1,234,567"},
+		{"1234567.891", "1,234,567.891"},
+		{"  12345", "  12,345"},
+		{"1e+21", "1e+21"},
+	} {
+		if got := groupDigits(tt.s, ','); got != tt.want {
+			t.Errorf("groupDigits(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestOptionsGroupDigits(t *testing.T) {
+	b := New(Options{
+		Padding:      1,
+		PadChar:      '.',
+		GroupDigits:  true,
+		ColumnAlign:  []Align{AlignRight, AlignRight},
+		ColumnFormat: []string{"", "%.2f"},
+	})
+	b.AddRow(1234567, 1234567.891)
+	b.AddRow(-98765, -0.5)
+	b.AddRow(uint8(255), float32(1000))
+	b.AddRow("123456", 0.25)
+	b.AddRow(money(123456))
+	testOutput(t, b, `
+1,234,567.1,234,567.89
+..-98,765........-0.50
+......255.....1,000.00
+...123456.........0.25
+.$1234.56
+`)
+
+	b = New(Options{GroupDigits: true, GroupSep: '_'})
+	b.AddRow(int64(-1) << 40)
+	testOutput(t, b, "-1_099_511_627_776\n")
+
+	b = New(Options{
+		Padding:      1,
+		PadChar:      '.',
+		GroupDigits:  true,
+		ColumnFormat: []string{"%x", "%08d", "%+d", "%6.1f", "%v"},
+	})
+	b.AddRow(0x123456, 1234, 1234, 1234.5, 1234)
+	testOutput(t, b, `
+123456.00001234.+1,234.1,234.5.1,234
+`)
+}
+
+func TestFormatVerb(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		flags  string
+		verb   byte
+	}{
+		{"", "", 0},
+		{"%d", "", 'd'},
+		{"%08.2f", "0", 'f'},
+		{"%-+5d", "-+", 'd'},
+		{"100%% %x", "", 'x'},
+		{"%[1]*d", "", 'd'},
+		{"no verb", "", 0},
+		{"%", "", 0},
+	} {
+		flags, verb := formatVerb(tt.format)
+		if flags != tt.flags || verb != tt.verb {
+			t.Errorf("formatVerb(%q): got %q, %q; want %q, %q", tt.format, flags, verb, tt.flags, tt.verb)
+		}
+	}
+}
+
+func TestTimeLayout(t *testing.T) {
+	when := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+	later := when.Add(36 * time.Hour)
+	var none *time.Time
+	b := New(Options{Padding: 1, PadChar: '.', TimeLayout: "2006-01-02", NilText: "never"})
+	b.SetHeader("event", "date", "n")
+	b.AddRow("start", when, 1)
+	b.AddRow("end", Right(&later), 2)
+	b.AddRow("x", none, 3)
+	testOutput(t, b, `
+event.date.......n
+-----.----------.-
+start.2021-03-04.1
+end...2021-03-05.2
+x.....never......3
+`)
+
+	b = New(Options{})
+	b.AddRow(when)
+	testOutput(t, b, "2021-03-04 05:06:07 +0000 UTC\n")
+}
+
+func TestErrorPrefix(t *testing.T) {
+	var noErr error
+	rows := [][]interface{}{
+		{"a", errors.New("bad thing"), 1},
+		{"b", noErr, 2},
+		{"c", "fine", 3},
+	}
+	b := New(Options{Padding: 1, PadChar: '.', ErrorPrefix: "error: "})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+a.error: bad thing.1
+b..................2
+c.fine.............3
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', ErrorStyle: "\x1b[31m", NilText: "-"})
+	b.AddRows(rows...)
+	testOutput(t, b, "a.\x1b[31mbad thing\x1b[0m.1\n"+
+		"b.-.........2\n"+
+		"c.fine......3\n")
+
+	b = New(Options{Padding: 1, PadChar: '.'})
+	b.AddRows(rows...)
+	testOutput(t, b, `
+a.bad thing.1
+b.<nil>.....2
+c.fine......3
+`)
+}
+
+type testErr struct{ msg string }
+
+func (e *testErr) Error() string { return e.msg }
+
+func TestErrorPrefixNilPointer(t *testing.T) {
+	var e *testErr
+	b := New(Options{Padding: 1, PadChar: '.', ErrorPrefix: "error: ", NilText: "-"})
+	b.AddRow("a", e, 1)
+	b.AddRow("b", &testErr{"bad"}, 2)
+	testOutput(t, b, `
+a.-..........1
+b.error: bad.2
+`)
+}
+
+func TestJustify(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("w", "the quick brown fox jumps", "x")
+	b.AddRow(1, Justify("the quick brown fox"), "y")
+	b.AddRow(2, Justify("single"), "z")
+	b.AddRow(3, Justify("  two  words "), "w")
+	b.AddRow(4, Justify("tab\tbed"))
+	testOutput(t, b, `
+w.the quick brown fox jumps.x
+1.the   quick   brown   fox.y
+2.single....................z
+3.  two              words .w
+4.tab	bed
+`)
+}
+
+func TestMaxWidth(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', MaxWidth: 5})
+	b.AddRow("short", "x")
+	b.AddRow("much too long", "y")
+	b.AddRow(Right("世界你好世界"), "z")
+	b.AddRow("\x1b[31mcolorful\x1b[0m", "w")
+	testOutput(t, b, "short.x\n"+
+		"much….y\n"+
+		"世界你好….z\n"+
+		"\x1b[31mcolo…\x1b[0m.w\n")
+}
+
+func TestMaxWidthTruncateMode(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', MaxWidth: 9, TruncateMode: TruncateMiddle})
+	b.AddRow("/usr/local/bin/tool", "x")
+	b.AddRow("/tmp", "y")
+	testOutput(t, b, `
+/usr…tool.x
+/tmp......y
+`)
+}
+
+func TestMaxTableWidth(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: ' ', MaxTableWidth: 30})
+	b.SetHeader("id", "name", "description")
+	b.AddRow(1, "a very long name here", "short")
+	b.AddRow(22, "bob", Right("quite long text"))
+	b.AddRow(3, "carol", "medium-ish")
+	// The natural width is 2+2+21+2+15 = 42.
+	testOutput(t, b, `
+id  name          description
+--  ------------  ------------
+1   a very long…  short
+22  bob           quite long …
+3   carol         medium-ish
+`)
+	for line := range b.Lines() {
+		if w := StringWidth(line); w > 30 {
+			t.Errorf("line %q has width %d", line, w)
+		}
+	}
+	if w, _ := b.Size(); w != 30 {
+		t.Errorf("Size() width = %d; want 30", w)
+	}
+}
+
+func TestMaxTableWidthCSI(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', MaxTableWidth: 6})
+	b.AddRow("\x1b[31mlongred\x1b[0m", "x")
+	b.AddRow("ab", "y")
+	testOutput(t, b, "\x1b[31mlon…\x1b[0m.x\n"+
+		"ab...y\n")
+}
+
+func TestMaxTableWidthFloor(t *testing.T) {
+	b := New(Options{
+		Padding:        1,
+		PadChar:        '.',
+		MaxTableWidth:  5,
+		ColumnMinWidth: []int{4},
+	})
+	b.AddRow("abcdef", "ghijkl", "mn")
+	testOutput(t, b, `
+abc….….…
+`)
+}
+
+func TestFitTerminal(t *testing.T) {
+	defer func(f func(io.Writer) (int, bool)) { terminalWidth = f }(terminalWidth)
+	var fake bytes.Buffer
+	terminalWidth = func(w io.Writer) (int, bool) {
+		if w == &fake {
+			return 12, true
+		}
+		return 0, false
+	}
+
+	b := New(Options{Padding: 1, PadChar: '.', FitTerminal: true})
+	b.AddRow("id", "a long description")
+	b.AddRow(1, "short")
+	if _, err := b.WriteTo(&fake); err != nil {
+		t.Fatal(err)
+	}
+	want := "id.a long d…\n" +
+		"1..short\n"
+	if got := fake.String(); got != want {
+		t.Errorf("writing to a terminal: got\n%s\nwant\n%s", got, want)
+	}
+
+	var other bytes.Buffer
+	if _, err := b.WriteTo(&other); err != nil {
+		t.Fatal(err)
+	}
+	want = "id.a long description\n" +
+		"1..short\n"
+	if got := other.String(); got != want {
+		t.Errorf("writing to a non-terminal: got\n%s\nwant\n%s", got, want)
+	}
+
+	b.opts.MaxTableWidth = 8
+	fake.Reset()
+	if _, err := b.WriteTo(&fake); err != nil {
+		t.Fatal(err)
+	}
+	want = "id.a lo…\n" +
+		"1..short\n"
+	if got := fake.String(); got != want {
+		t.Errorf("with a smaller MaxTableWidth: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 4})
+	b.AddRow("a", "abcdefghij", "b")
+	b.AddRow("c", "d", "e")
+	testOutput(t, b, `
+a.abcd.b
+..efgh
+..ij
+c.d....e
+`)
+}
+
+func TestWrapStyled(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 5})
+	b.AddRow("\x1b[31mhello world\x1b[0m", "next")
+	testOutput(t, b, "\x1b[31mhello\x1b[0m.next\n"+
+		"\x1b[31m worl\x1b[0m\n"+
+		"\x1b[31md\x1b[0m\n")
+}
+
+func TestWrapMultiple(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 3})
+	b.AddRow("abcdefg", "x", "hijk", "y")
+	b.AddRow("a", "b", "c", "d")
+	testOutput(t, b, `
+abc.x.hij.y
+def...k
+g
+a...b.c...d
+`)
+}
+
+func TestWrapRight(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 3})
+	b.AddRow(Right("abcdefg"), Right("世界"))
+	b.AddRow("a", Right("égalité"))
+	testOutput(t, b, `
+abc..世界
+def
+..g
+a...éga
+....lit
+......é
+`)
+}
+
+func TestHeader(t *testing.T) {
+	b := New(Options{MinWidth: 4, Padding: 2, PadChar: ' '})
+	b.SetHeader("x", Right("x²"), "note")
+	b.AddRow(1, Right(1), "odd")
+	b.AddRow(12, Right(144), "even number")
+	testOutput(t, b, `
+x       x²  note
+----  ----  -----------
+1        1  odd
+12     144  even number
+`)
+}
+
+func TestHeaderRule(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', HeaderRule: '=', HeaderRuleSpansPadding: true})
+	b.SetHeader("name", "count")
+	b.AddRow("alpha", Right(3))
+	b.AddRow("b", Right(10), "extra")
+	testOutput(t, b, `
+name  count
+=================
+alpha     3
+b        10 extra
+`)
+}
+
+func TestHeaderBorder(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderUnicode})
+	b.SetHeader("name", "count")
+	b.AddRow("alpha", Right(3))
+	testOutput(t, b, `
+┌───────┬───────┐
+│ name  │ count │
+├───────┼───────┤
+│ alpha │     3 │
+└───────┴───────┘
+`)
+}
+
+func TestMultiLine(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("a", "two\nlines", Right("x"), "y")
+	b.AddRow("b", "c", Right("three\nline\ncell"))
+	b.AddRow("d", "e", "f", "g")
+	testOutput(t, b, `
+a.two.......x.y
+..lines
+b.c.....three
+.........line
+.........cell
+d.e.....f.....g
+`)
+}
+
+func TestMultiLineWrap(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 3, MaxWidth: 5})
+	b.AddRow("abcd\nefghijkl", "x")
+	testOutput(t, b, `
+abc.x
+d
+efg
+h…
+`)
+}
+
+func TestTabWidth(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', TabWidth: 4})
+	b.AddRow("a\tb", "x")
+	b.AddRow("\t", "y")
+	b.AddRow("abcd\te", Right("f\tg"))
+	testOutput(t, b, `
+a   b.....x
+    ......y
+abcd    e.f   g
+`)
+}
+
+func TestCountCSI(t *testing.T) {
+	for _, tt := range []struct {
+		countCSI bool
+		want     string
+	}{
+		{false, "\x1b[1mbold\x1b[0m..x\nplain.y\n"},
+		{true, "\x1b[1mbold\x1b[0m.x\nplain........y\n"},
+	} {
+		b := New(Options{Padding: 1, PadChar: '.', CountCSI: tt.countCSI})
+		b.AddRow("\x1b[1mbold\x1b[0m", "x")
+		b.AddRow("plain", "y")
+		testOutput(t, b, tt.want)
+	}
+}
+
+func TestEastAsianWidth(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', EastAsianWidth: true})
+	b.AddRow("→", "x")
+	b.AddRow("ab", "y")
+	b.AddRow("世界", "z")
+	testOutput(t, b, `
+→...x
+ab...y
+世界.z
+`)
+}
+
+func TestGraphemeWidth(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', GraphemeWidth: true})
+	b.AddRow("👨\u200d👩\u200d👧", "x")
+	b.AddRow("ab", "y")
+	b.AddRow("世", "z")
+	testOutput(t, b, "👨\u200d👩\u200d👧.x\n"+
+		"ab.y\n"+
+		"世..z\n")
+}
+
+func TestReverseColumns(t *testing.T) {
+	build := func(opts Options) *Buffer {
+		b := New(opts)
+		b.SetHeader("name", "count", "note")
+		b.AddRow("apple", Right(1), "red")
+		b.AddRow("banana", Right(22))
+		b.AddRow("kiwi", Right(333), "small and fuzzy")
+		return b
+	}
+	testOutput(t, build(Options{Padding: 1, PadChar: '.'}), `
+name...count.note
+------.-----.---------------
+apple......1.red
+banana....22
+kiwi.....333.small and fuzzy
+`)
+	testOutput(t, build(Options{Padding: 1, PadChar: '.', ReverseColumns: true, ColumnMinWidth: []int{8}}), `
+note............count.name
+---------------.-----.--------
+red.................1.apple
+...................22.banana
+small and fuzzy...333.kiwi
+`)
+	testOutput(t, build(Options{Padding: 1, PadChar: '.', ReverseColumns: true, RowNumbers: true}), `
+#.note............count.name
+-.---------------.-----.------
+1.red.................1.apple
+2....................22.banana
+3.small and fuzzy...333.kiwi
+`)
+}
+
+func TestColumnOrder(t *testing.T) {
+	b := New(Options{
+		Padding:     1,
+		PadChar:     '.',
+		ColumnOrder: []int{2, 0, 1},
+		ColumnAlign: []Align{AlignLeft, AlignRight},
+	})
+	b.SetHeader("name", "count", "note")
+	b.AddRow("apple", 1, "red")
+	b.AddRow("banana", 22)
+	b.AddRow("kiwi", 333, Right("small"))
+	testOutput(t, b, `
+note..name...count
+-----.------.-----
+red...apple......1
+......banana....22
+small.kiwi.....333
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', ColumnOrder: []int{3, 1}})
+	b.AddRow("a", "b")
+	b.AddRow("c", "d", "e", "f")
+	testOutput(t, b, `
+..b
+f.d
+`)
+}
+
+func TestCollapseColumns(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', CollapseColumns: []int{0}})
+	b.SetHeader("category", "item")
+	b.AddRow("fruit", "apple")
+	b.AddRow("fruit", "banana")
+	b.AddRow("fruit", "kiwi")
+	b.AddRow("vegetable", "carrot")
+	b.AddRow("vegetable", "leek")
+	b.AddRule()
+	b.AddRow("vegetable", "onion")
+	testOutput(t, b, `
+category..item
+---------.------
+fruit.....apple
+..........banana
+..........kiwi
+vegetable.carrot
+..........leek
+---------.------
+vegetable.onion
+`)
+}
+
+func TestVerticalAlign(t *testing.T) {
+	build := func(va VAlign) *Buffer {
+		b := New(Options{Padding: 1, PadChar: '.', VerticalAlign: va})
+		b.AddRow("one\ntwo\nthree", "x", "a\nb", Right(1))
+		b.AddRow("four", "y")
+		return b
+	}
+	testOutput(t, build(VAlignTop), `
+one...x.a.1
+two.....b
+three
+four..y
+`)
+	testOutput(t, build(VAlignMiddle), `
+one.....a
+two...x.b.1
+three
+four..y
+`)
+	testOutput(t, build(VAlignBottom), `
+one
+two.....a
+three.x.b.1
+four..y
+`)
+
+	b := New(Options{Padding: 1, PadChar: '.', VerticalAlign: VAlignBottom, Border: BorderASCII})
+	b.AddRow("a\nb", "x")
+	testOutput(t, b, `
++---+---+
+|.a.|...|
+|.b.|.x.|
++---+---+
 `)
 }
 
+func TestWriteToWidths(t *testing.T) {
+	b := New(Options{MinWidth: 3, Padding: 1, PadChar: '.', RowNumbers: true})
+	b.SetColumnWidths([]int{-1, 6})
+	b.SetHeader("name", "count", "x")
+	b.AddRow("banana", Right(22), "y")
+	var buf bytes.Buffer
+	widths, n, err := b.WriteToWidths(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `
+..#.name...count..x
+---.------.------.---
+..1.banana.....22.y
+`[1:]
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("got n = %d; want %d", n, len(want))
+	}
+	if diff := cmp.Diff(widths, []int{3, 6, 6, 3}); diff != "" {
+		t.Errorf("wrong widths (-got, +want):\n%s", diff)
+	}
+	// The widths match those of the header rule.
+	for i, rule := range strings.Split(strings.Split(want, "\n")[1], ".") {
+		if len(rule) != widths[i] {
+			t.Errorf("column %d: rule is %d wide; width is %d", i, len(rule), widths[i])
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, opts := range []Options{
+		{Padding: 2, PadChar: '.', Indent: 3, RowPrefix: "> "},
+		{Padding: 1, PadChar: ' ', Border: BorderASCII, RowNumbers: true},
+		{Padding: 1, PadChar: '.', ColumnSeparator: "|", VerticalAlign: VAlignBottom},
+	} {
+		b := New(opts)
+		b.SetTitle("title")
+		b.SetHeader("name", Right("count"), "note")
+		b.AddRow("apple", Right(1), Center("red"))
+		b.AddRule()
+		b.AddRow("banana", Right(22), "")
+		b.AddSpanRow(Center("span"))
+		b.AddRow("kiwi", Right(333), "small\nand fuzzy")
+		want := [][]string{
+			{"name", "count", "note"},
+			{"apple", "1", "red"},
+			{"banana", "22", ""},
+			{"span"},
+			{"kiwi", "333", "small\nand fuzzy"},
+		}
+		if opts.RowNumbers {
+			want[0] = append([]string{"#"}, want[0]...)
+			want[1] = append([]string{"1"}, want[1]...)
+			want[2] = append([]string{"2"}, want[2]...)
+			want[4] = append([]string{"3"}, want[4]...)
+		}
+		// Read the text at each position from the table.
+		lines := strings.Split(b.String(), "\n")
+		var got [][]string
+		for _, row := range b.Layout() {
+			var cells []string
+			for _, p := range row {
+				var text []string
+				for k := p.Line; k < p.Line+p.Height; k++ {
+					// Lines may end early, without padding.
+					line := lines[k] + strings.Repeat(" ", p.Start+p.Width)
+					text = append(text, strings.TrimRight(line[p.Start:p.Start+p.Width], " ."))
+				}
+				cells = append(cells, strings.Join(text, "\n"))
+			}
+			got = append(got, cells)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s(-got, +want):\n%s", b, diff)
+		}
+	}
+
+	b := New(Options{Padding: 2, PadChar: '.', Indent: 3, RowPrefix: "> "})
+	b.SetTitle("title")
+	b.SetHeader("name", Right("count"))
+	b.AddRow("apple", Right(1))
+	b.AddRule()
+	b.AddRow("kiwi\nfruit", "")
+	b.AddSpanRow(Center("span"))
+	got := b.Layout()
+	want := [][]CellPosition{
+		{{1, 5, 4, 1}, {1, 12, 5, 1}},
+		{{3, 5, 5, 1}, {3, 16, 1, 1}},
+		{{5, 5, 5, 2}, {5, 12, 0, 1}},
+		{{7, 9, 4, 1}},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("wrong layout (-got, +want):\n%s", diff)
+	}
+}
+
+func TestLockWidthsAfterFirstRow(t *testing.T) {
+	build := func(opts Options) *Buffer {
+		opts.Padding = 1
+		opts.PadChar = '.'
+		opts.MinWidth = 3
+		opts.LockWidthsAfterFirstRow = true
+		b := New(opts)
+		b.SetHeader("name", "n")
+		b.AddRow("kiwi", Right(1))
+		b.AddRow("watermelon", Right(5000), "extra")
+		b.AddRow("fig", Right(22), "x")
+		return b
+	}
+	testOutput(t, build(Options{}), `
+name.n
+----.---.-----
+kiwi...1
+watermelon.5000.extra
+fig...22.x
+`)
+	testOutput(t, build(Options{TruncateFixedWidths: true}), `
+name.n
+----.---.-----
+kiwi...1
+wat….50….extra
+fig...22.x
+`)
+
+	b := New(Options{Padding: 1, PadChar: '.', LockWidthsAfterFirstRow: true})
+	b.AddRow()
+	b.AddRow("kiwi", 1)
+	b.AddRow("watermelon", 5000)
+	testOutput(t, b, `
+
+kiwi.1
+watermelon.5000
+`)
+}
+
+func TestTitle(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', TitleAlign: AlignCenter})
+	b.SetTitle("\x1b[1mFruit\x1b[0m")
+	b.SetCaption("3 rows")
+	b.SetHeader("name", "count", "note")
+	b.AddRow("apple", Right(1), "red")
+	b.AddRow("banana", Right(22))
+	b.AddRow("kiwi", Right(333), "fuzzy")
+	testOutput(t, b, ".......\x1b[1mFruit\x1b[0m"+`
+name....count..note
+------..-----..-----
+apple.......1..red
+banana.....22
+kiwi......333..fuzzy
+.......3 rows
+`)
+	if w, h := b.Size(); w != 20 || h != 7 {
+		t.Errorf("Size: got (%d, %d); want (20, 7)", w, h)
+	}
+
+	b = New(Options{Padding: 1, PadChar: '.', TitleAlign: AlignRight, Border: BorderASCII})
+	b.SetTitle("a long title")
+	b.AddRow("a", "b")
+	testOutput(t, b, `
+a long title
++---+---+
+|.a.|.b.|
++---+---+
+`)
+}
+
+func TestIndent(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', Indent: 4})
+	b.SetHeader("name", "count")
+	b.AddRow("apple", Right(1))
+	b.AddRow()
+	b.AddRule()
+	b.AddRow("kiwi", Right(333))
+	testOutput(t, b, `
+....name..count
+....-----.-----
+....apple.....1
+....
+....-----.-----
+....kiwi....333
+`)
+	if w, h := b.Size(); w != 15 || h != 6 {
+		t.Errorf("Size: got (%d, %d); want (15, 6)", w, h)
+	}
+
+	b = New(Options{Padding: 1, PadChar: ' ', Indent: 4, Border: BorderASCII})
+	b.AddRow("a", "b")
+	testOutput(t, b, `
+    +---+---+
+    | a | b |
+    +---+---+
+`)
+}
+
+func TestCellStyle(t *testing.T) {
+	type call struct {
+		row, col int
+		text     string
+	}
+	var calls []call
+	b := New(Options{
+		Padding:    1,
+		PadChar:    '.',
+		RowNumbers: true,
+		CellStyle: func(row, col int, text string) string {
+			calls = append(calls, call{row, col, text})
+			if strings.HasPrefix(text, "-") {
+				return "\x1b[31m" + text + "\x1b[0m"
+			}
+			return text
+		},
+	})
+	b.SetHeader("name", "change")
+	b.AddRow("apple", Right(-12))
+	b.AddRow("kiwi", Right(3))
+	b.AddRule()
+	b.AddRow("pear", Decimal("-1.5"))
+	b.SetFooter("total", Right(-10.5))
+	want := "" +
+		"#.name..change\n" +
+		"-.-----.------\n" +
+		"1.apple....\x1b[31m-12\x1b[0m\n" +
+		"2.kiwi.......3\n" +
+		"-.-----.------\n" +
+		"3.pear....\x1b[31m-1.5\x1b[0m\n" +
+		"-.-----.------\n" +
+		"..total..\x1b[31m-10.5\x1b[0m\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+	wantCalls := []call{
+		{-1, -1, "#"}, {-1, 0, "name"}, {-1, 1, "change"},
+		{0, -1, "1"}, {0, 0, "apple"}, {0, 1, "-12"},
+		{1, -1, "2"}, {1, 0, "kiwi"}, {1, 1, "3"},
+		{3, -1, "3"}, {3, 0, "pear"}, {3, 1, "-1.5"},
+		{4, -1, ""}, {4, 0, "total"}, {4, 1, "-10.5"},
+	}
+	if diff := cmp.Diff(calls, wantCalls, cmp.AllowUnexported(call{})); diff != "" {
+		t.Errorf("wrong calls (-got, +want):\n%s", diff)
+	}
+}
+
+func TestRowPrefixSuffix(t *testing.T) {
+	b := New(Options{
+		Padding:     2,
+		PadChar:     '.',
+		PadLastCell: true,
+		RowPrefix:   "| ",
+		RowSuffix:   " |",
+	})
+	b.SetHeader("name", Right("count"))
+	b.AddRow("apple", Right(1))
+	b.AddRow("kiwi", Right(333))
+	testOutput(t, b, `
+| name...count |
+| -----..----- |
+| apple......1 |
+| kiwi.....333 |
+`)
+	if w, _ := b.Size(); w != 16 {
+		t.Errorf("Size: got width %d; want 16", w)
+	}
+
+	b = New(Options{Padding: 1, PadChar: '.', Indent: 2, RowPrefix: "> ", RowSuffix: "<"})
+	b.AddRow("a", "bb")
+	b.AddRow("ccc")
+	testOutput(t, b, `
+..> a...bb<
+..> ccc<
+`)
+}
+
+func TestHideColumns(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', HideColumns: []int{1, 5}})
+	b.SetHeader("name", "secret", "count")
+	b.AddRow("apple", "a-very-long-value", Right(1))
+	b.AddRow("banana", "x")
+	b.AddRow("kiwi", "y", Right(333))
+	testOutput(t, b, `
+name...count
+------.-----
+apple......1
+banana
+kiwi.....333
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', HideColumns: []int{0}, ReverseColumns: true})
+	b.AddRow("apple", "red", 1)
+	b.AddRow("kiwi", "small and fuzzy", 333)
+	testOutput(t, b, `
+1...red
+333.small and fuzzy
+`)
+}
+
+func TestTrimCells(t *testing.T) {
+	build := func(opts Options) *Buffer {
+		opts.Padding = 1
+		opts.PadChar = '.'
+		b := New(opts)
+		b.AddRow(Right("foo   "), "  bar\t", "x")
+		b.AddRow(Right("a"), "b", "y")
+		return b
+	}
+	testOutput(t, build(Options{}), "foo   .  bar\t.x\n"+
+		".....a.b......y\n")
+	testOutput(t, build(Options{TrimCells: true}), "foo.  bar.x\n"+
+		"..a.b.....y\n")
+	testOutput(t, build(Options{TrimCellsFull: true}), `
+foo.bar.x
+..a.b...y
+`)
+}
+
+func TestNormalizeNFC(t *testing.T) {
+	build := func(opts Options) *Buffer {
+		opts.Padding = 1
+		opts.PadChar = '.'
+		b := New(opts)
+		b.AddRow("caf\u00e9", "precomposed")
+		b.AddRow("cafe\u0301", "decomposed")
+		return b
+	}
+	testOutput(t, build(Options{}), "caf\u00e9..precomposed\n"+
+		"cafe\u0301.decomposed\n")
+	testOutput(t, build(Options{NormalizeNFC: true}), "caf\u00e9.precomposed\n"+
+		"caf\u00e9.decomposed\n")
+}
+
+func TestNewChecked(t *testing.T) {
+	if _, err := NewChecked(Options{
+		MinWidth:       2,
+		Padding:        1,
+		ColumnAlign:    []Align{AlignRight, AlignJustify},
+		ColumnMinWidth: []int{0, 3},
+		Border:         BorderUnicode,
+		TruncateMode:   TruncateMiddle,
+		VerticalAlign:  VAlignBottom,
+	}); err != nil {
+		t.Fatalf("NewChecked with valid options: %s", err)
+	}
+	for _, tt := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{Padding: -1}, "tabular: negative Padding (-1)"},
+		{Options{MinWidth: -2}, "tabular: negative MinWidth (-2)"},
+		{Options{MaxWidth: -1}, "tabular: negative MaxWidth (-1)"},
+		{Options{Indent: -4}, "tabular: negative Indent (-4)"},
+		{Options{ColumnMinWidth: []int{1, -1}}, "tabular: negative ColumnMinWidth[1] (-1)"},
+		{Options{ColumnPadding: []int{-3}}, "tabular: negative ColumnPadding[0] (-3)"},
+		{Options{HideColumns: []int{0, -1}}, "tabular: negative HideColumns[1] (-1)"},
+		{Options{ColumnAlign: []Align{AlignLeft, 17}}, "tabular: invalid ColumnAlign[1] (17)"},
+		{Options{TitleAlign: -1}, "tabular: invalid TitleAlign (-1)"},
+		{Options{VerticalAlign: 3}, "tabular: invalid VerticalAlign (3)"},
+		{Options{TruncateMode: 5}, "tabular: invalid TruncateMode (5)"},
+		{Options{Border: 9}, "tabular: invalid Border (9)"},
+		{Options{WrapMode: 2}, "tabular: invalid WrapMode (2)"},
+	} {
+		b, err := NewChecked(tt.opts)
+		if err == nil {
+			t.Errorf("NewChecked(%+v): got nil error; want %q", tt.opts, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("NewChecked(%+v): got error %q; want %q", tt.opts, err, tt.want)
+		}
+		if b != nil {
+			t.Errorf("NewChecked(%+v): got non-nil Buffer with error", tt.opts)
+		}
+	}
+}
+
+func TestNegativeOptions(t *testing.T) {
+	b := New(Options{Padding: -1, Indent: -2, PadChar: '.', Border: BorderASCII})
+	b.SetHeader("name", "n")
+	b.AddRow("kiwi", Right(1))
+	testOutput(t, b, `
++----+-+
+|name|n|
++----+-+
+|kiwi|1|
++----+-+
+`)
+
+	b = New(Options{
+		Padding:        1,
+		PadChar:        '.',
+		Indent:         -1,
+		IndentWidth:    -2,
+		MinWidth:       -3,
+		MaxWidth:       -1,
+		WrapWidth:      -1,
+		ColumnPadding:  []int{0, -2},
+		ColumnMinWidth: []int{-1},
+		HideColumns:    []int{-1},
+	})
+	b.AddIndentedRow(1, "kiwi", Right(1), "x")
+	testOutput(t, b, `
+kiwi1.x
+`)
+}
+
+func TestAppend(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", "count")
+	b.AddRow("apple", 1)
+	b.AddRow("kiwi", 2)
+	b.AddRow("pear", 3)
+
+	other := New(Options{Padding: 3, AlignRight: true})
+	other.SetHeader("ignored")
+	other.AddRow("watermelon", 40)
+	other.AddRule()
+	other.AddRow(Left("fig"), 5000)
+
+	b.Append(other)
+	other.SetCell(0, 0, "changed")
+	testOutput(t, b, `
+name.......count
+----------.-----
+apple......1
+kiwi.......2
+pear.......3
+watermelon....40
+----------.-----
+fig.........5000
+`)
+	if got, want := other.NumRows(), 3; got != want {
+		t.Errorf("other.NumRows(): got %d; want %d", got, want)
+	}
+}
+
+func TestAddBlankRow(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', Indent: 2, RowPrefix: "> ", StripeChar: '_'})
+	b.SetHeader("name", "count")
+	b.AddRow("apple", 1)
+	b.AddRow("kiwi", 2)
+	b.AddBlankRow()
+	b.AddRow("pear", 3)
+	testOutput(t, b, `
+..> name..count
+..> -----.-----
+..> apple.1
+..> kiwi__2
+..> 
+..> pear..3
+`)
+	if w, h := b.Size(); w != 15 || h != 6 {
+		t.Errorf("Size: got (%d, %d); want (15, 6)", w, h)
+	}
+
+	b = New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII})
+	b.AddRow("a", "b")
+	b.AddBlankRow()
+	b.AddRow()
+	b.AddRow("c", "d")
+	testOutput(t, b, `
++---+---+
+| a | b |
+
+|   |   |
+| c | d |
++---+---+
+`)
+	testCSV(t, b, "a,b\n\nc,d\n")
+}
+
+func TestWrapMode(t *testing.T) {
+	build := func(mode WrapMode) *Buffer {
+		b := New(Options{Padding: 1, PadChar: '.', WrapWidth: 8, WrapMode: mode})
+		b.AddRow("the quick brown fox jumps", "x")
+		b.AddRow("antidisestablishment", "y")
+		return b
+	}
+	testOutput(t, build(WrapRune), "the quic.x\n"+
+		"k brown \n"+
+		"fox jump\n"+
+		"s\n"+
+		"antidise.y\n"+
+		"stablish\n"+
+		"ment\n")
+	testOutput(t, build(WrapWord), `
+the......x
+quick
+brown
+fox
+jumps
+antidise.y
+stablish
+ment
+`)
+}
+
+func TestCells(t *testing.T) {
+	parts := []interface{}{"a", 1, "ccc"}
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("id", Cells("x", "y", "z"))
+	b.AddRow("first", Cells(parts...))
+	b.AddRow("second", Right(Cells("bb", Left(Cells(22, "d")))), "end")
+	b.AddRow(Cells())
+	b.AddRow(parts)
+	want := New(Options{Padding: 1, PadChar: '.'})
+	want.SetHeader("id", "x", "y", "z")
+	want.AddRow(append([]interface{}{"first"}, parts...)...)
+	want.AddRow("second", Right("bb"), Right(Left(22)), Right(Left("d")), "end")
+	want.AddRow()
+	want.AddRow(parts)
+	if got, want := b.String(), want.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	testOutput(t, b, `
+id........x..y..z
+---------.--.--.---.---
+first.....a..1..ccc
+second....bb.22.d...end
+
+[a 1 ccc]
+`)
+	b.SetCell(0, 0, Cells(1, 2))
+	if got, want := b.Cell(0, 0), "[1 2]"; got != want {
+		t.Errorf("Cell after SetCell(Cells): got %q; want %q", got, want)
+	}
+}
+
+func TestZeropad(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', GroupDigits: true})
+	b.AddRow(Zeropad(7, 3), "seven")
+	b.AddRow(Zeropad(42, 3), "forty-two")
+	b.AddRow(Right(Zeropad(uint16(1234), 3)), "more digits")
+	b.AddRow(Zeropad(Right(-5), 3), "negative")
+	b.AddRow(Zeropad(12345, 7), "not grouped")
+	b.AddRow(Zeropad("x", 3), "not a number")
+	b.AddRow(Zeropad(2.5, 3), "float")
+	b.AddRow(12345, "unpadded")
+	testOutput(t, b, `
+007.....seven
+042.....forty-two
+...1234.more digits
+....-05.negative
+0012345.not grouped
+x.......not a number
+2.5.....float
+12,345..unpadded
+`)
+}
+
+func TestPercent(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", Right("share"))
+	b.AddRow("half", Percent(0.5, 1))
+	b.AddRow("tiny", Percent(0.00123, 2))
+	b.AddRow("all", Percent(1, 0))
+	b.AddRow("over", Percent(2.5, 1))
+	b.AddRow("loss", Percent(float32(-0.05), 1))
+	b.AddRow("none", Percent(math.NaN(), 1))
+	b.AddRow("inf", Percent(math.Inf(1), 1))
+	b.AddRow("text", Percent("n/a", 1))
+	b.AddRow("left", Left(Percent(0.25, -1)))
+	testOutput(t, b, `
+name..share
+----.------
+half..50.0%
+tiny..0.12%
+all....100%
+over.250.0%
+loss..-5.0%
+none....NaN
+inf....+Inf
+text....n/a
+left.25%
+`)
+}
+
+func TestAppendRow(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", "count")
+	if got := b.AppendRow("apple", 1); got != 0 {
+		t.Errorf("first AppendRow: got %d; want 0", got)
+	}
+	b.AddRule()
+	i := b.AppendRow("kiwi", 0)
+	if i != 2 {
+		t.Errorf("AppendRow after AddRule: got %d; want 2", i)
+	}
+	b.SetCell(i, 1, 333)
+	testOutput(t, b, `
+name..count
+-----.-----
+apple.1
+-----.-----
+kiwi..333
+`)
+}
+
+func TestRender(t *testing.T) {
+	build := func(max int) *Buffer {
+		b := New(Options{Padding: 2, PadChar: '.', MaxTableWidth: max, ColumnMinWidth: []int{4, 4}})
+		b.AddRow("apple", "banana", "cherry")
+		b.AddRow("kiwi", "fig", "grape")
+		return b
+	}
+	b := build(15)
+	s, err := b.Render()
+	if err != nil {
+		t.Fatalf("Render: %s", err)
+	}
+	if want := b.String(); s != want {
+		t.Errorf("Render: got\n%s\nwant\n%s", s, want)
+	}
+
+	b = build(10)
+	s, err = b.Render()
+	if err == nil {
+		t.Fatal("Render with impossible MaxTableWidth: got nil error")
+	}
+	if got, want := err.Error(), "tabular: table is 13 columns wide, which exceeds MaxTableWidth (10)"; got != want {
+		t.Errorf("Render error: got %q; want %q", got, want)
+	}
+	if want := b.String(); s != want {
+		t.Errorf("Render with error: got\n%s\nwant\n%s", s, want)
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII, CRLF: true})
+	b.SetTitle("fruit")
+	b.SetHeader("name", "count")
+	b.AddRow("apple", "1\n2")
+	b.AddRule()
+	b.AddBlankRow()
+	b.AddSpanRow("span")
+	b.SetFooter("total", 3)
+	got := b.String()
+	want := "fruit\r\n" +
+		"+-------+-------+\r\n" +
+		"| name  | count |\r\n" +
+		"+-------+-------+\r\n" +
+		"| apple | 1     |\r\n" +
+		"|       | 2     |\r\n" +
+		"+-------+-------+\r\n" +
+		"\r\n" +
+		"| span          |\r\n" +
+		"+-------+-------+\r\n" +
+		"| total | 3     |\r\n" +
+		"+-------+-------+\r\n"
+	if got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+	if strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+		t.Errorf("some lines don't end with CRLF: %q", got)
+	}
+	var lines []string
+	for line := range b.Lines() {
+		lines = append(lines, line)
+	}
+	if diff := cmp.Diff(lines, strings.Split(strings.TrimSuffix(want, "\r\n"), "\r\n")); diff != "" {
+		t.Errorf("Lines (-got, +want):\n%s", diff)
+	}
+}
+
+func TestTrimTrailing(t *testing.T) {
+	b := New(Options{MinWidth: 3, Padding: 2, PadChar: '.', TrimTrailing: true})
+	b.AddRow("this", "", "")
+	b.AddRow("a", "b", "c")
+	b.AddRow("sp  ", "")
+	b.AddRow("", Right("\x1b[1mx\x1b[0m"), "")
+	testOutput(t, b, "this\n"+
+		"a.....b....c\n"+
+		"sp  \n"+
+		"........\x1b[1mx\x1b[0m\n")
+}
+
+func TestPadLastCell(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', PadLastCell: true})
+	b.SetHeader("name", "size", "note")
+	b.AddRow("a", Right(1), Right("x"))
+	b.AddRow("bb", Right(22))
+	b.AddRow("c\nd", 3, Center("yyyy"))
+	b.AddSpanRow(Right("total"))
+	testOutput(t, b, `
+name.size.note
+----.----.----
+a.......1....x
+bb.....22.....
+c....3....yyyy
+d.............
+.........total
+`)
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		if got, want := StringWidth(line), 14; got != want {
+			t.Errorf("line %q has width %d; want %d", line, got, want)
+		}
+	}
+}
+
+func TestAddRule(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', HeaderRule: '='})
+	b.SetHeader("name", "n")
+	b.AddRow("apple", 1)
+	b.AddRow("banana", 22)
+	b.AddRule()
+	b.AddRow("carrot", 333)
+	b.AddRow("longer name than the rest", 4)
+	b.AddRule()
+	testOutput(t, b, `
+name.......................n
+=========================..===
+apple......................1
+banana.....................22
+=========================..===
+carrot.....................333
+longer name than the rest..4
+=========================..===
+`)
+}
+
+func TestAddRuleOptions(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', RuleChar: '~', RuleSpansPadding: true})
+	b.AddRow("a", "b")
+	b.AddRule()
+	b.AddRow("cc", "dd")
+	b.AddSpanRow("a long span row")
+	b.AddRow("e", "f")
+	testOutput(t, b, `
+a  b
+~~~~~
+cc dd
+a long span row
+e  f
+`)
+
+	b = New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII})
+	b.AddRow("a", "b")
+	b.AddRule()
+	b.AddRow("cc", "dd")
+	testOutput(t, b, `
++----+----+
+| a  | b  |
++----+----+
+| cc | dd |
++----+----+
+`)
+}
+
+func TestAddIndentedRow(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddIndentedRow(0, "src", "dir")
+	b.AddIndentedRow(1, "main.go", "file")
+	b.AddIndentedRow(1, "util", "dir")
+	b.AddIndentedRow(2, "util.go", "file")
+	b.AddIndentedRow(0, "README", "file")
+	testOutput(t, b, `
+src.........dir
+  main.go...file
+  util......dir
+    util.go.file
+README......file
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', IndentWidth: 1})
+	b.AddIndentedRow(0, Right("a"), 1)
+	b.AddIndentedRow(2, Right("b"), 2)
+	b.AddIndentedRow(1, "c\nd", 3)
+	testOutput(t, b, `
+..a.1
+  b.2
+ c..3
+ d
+`)
+}
+
+func TestStripeChar(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', StripeChar: '.'})
+	b.SetHeader("name", "count", "x")
+	b.AddRow("a", Right(1), "x")
+	b.AddRow("b", Right(22), "y")
+	b.AddSpanRow("span")
+	b.AddRow("c", Center(3), "z")
+	b.AddRow("d", Right(4444), "w")
+	testOutput(t, b, `
+name count x
+---- ----- -
+a        1 x
+b.......22.y
+span
+c      3   z
+d.....4444.w
+`)
+}
+
+func TestAddSumFooter(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("item", "count", "price", "weight")
+	b.AddRow("a", 3, 1.25, "n/a")
+	b.AddRow("b", 10, 2, 7)
+	b.AddSpanRow("99")
+	b.AddRow("c", "unknown", -0.5, 3)
+	b.AddRow("d")
+	b.AddSumFooter(1, 2, 3)
+	testOutput(t, b, `
+item.count...price.weight
+----.-------.-----.------
+a....3.......1.25..n/a
+b....10......2.....7
+99
+c....unknown.-0.5..3
+d
+----.-------.-----.------
+..........13..2.75.....10
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', GroupDigits: true})
+	b.AddRow("a", 1500, 0.1)
+	b.AddRow("b", 2500, 0.2)
+	b.AddRow("c", 1000000, "x")
+	b.AddSumFooter(-1, 1, 2)
+	testOutput(t, b, `
+a.1,500.....0.1
+b.2,500.....0.2
+c.1,000,000.x
+-.---------.---
+..1,004,000.0.3
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', ColumnFormat: []string{"%.0f", "%03d"}})
+	b.AddRow(1.2, 7)
+	b.AddRow(2.4, 5)
+	b.AddSumFooter(0, 1)
+	testOutput(t, b, `
+1.007
+2.005
+-.---
+3.012
+`)
+}
+
+func TestAddRows(t *testing.T) {
+	opts := Options{Padding: 2, PadChar: '.'}
+	b0 := New(opts)
+	b0.AddRow("this", "is", Right("a"), "test")
+	b0.AddRow(1, 2, Right(true))
+	b1 := New(opts)
+	b1.AddRows(
+		[]interface{}{"this", "is", Right("a"), "test"},
+		[]interface{}{1, 2, Right(true)},
+	)
+	if got, want := b1.String(), b0.String(); got != want {
+		t.Errorf("AddRows: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCellf(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', ColumnFormat: []string{"%d", "%d"}})
+	b.AddRow(Cellf("%.2f", 3.14159), Right(Cellf("%d%%", 50)), "x")
+	b.AddRow(12, Left(Cellf("%s-%s", "a", Right("b"))), "y")
+	b.AddRow(Center(Cellf("%x", 255)), 100, "z")
+	testOutput(t, b, `
+3.14.50%.x
+12...a-b.y
+.ff..100.z
+`)
+}
+
+func TestFprint(t *testing.T) {
+	opts := Options{Padding: 2, PadChar: '.'}
+	rows := [][]interface{}{
+		{"this", "is", Right("a"), "test"},
+		{1, Center(2), Right(true)},
+		{Left("x")},
+	}
+	b := New(opts)
+	for _, vs := range rows {
+		b.AddRow(vs...)
+	}
+	var buf bytes.Buffer
+	n, err := Fprint(&buf, opts, rows...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), b.String(); got != want {
+		t.Errorf("Fprint: got\n%s\nwant\n%s", got, want)
+	}
+	if int(n) != buf.Len() {
+		t.Errorf("Fprint returned %d; wrote %d bytes", n, buf.Len())
+	}
+}
+
+func TestColumnFormat(t *testing.T) {
+	b := New(Options{
+		Padding:      1,
+		PadChar:      '.',
+		ColumnFormat: []string{"", "%.2f", "%04d"},
+	})
+	b.AddRow("pi", Right(3.14159), 7, 1.5)
+	b.AddRow("e", Right(2.71828), Right(12345))
+	b.AddRow("bad", "x", 1.5)
+	testOutput(t, b, `
+pi...........3.14.0007..............1.5
+e............2.72.............12345
+bad.%!f(string=x).%!d(float64=01.5)
+`)
+}
+
+func TestSortByColumn(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", "n")
+	b.AddRow("b", 10)
+	b.AddRow("a", 9)
+	b.AddRow("c")
+	b.AddRow("a", 100)
+	b.SortByColumn(0, func(x, y string) bool { return x < y })
+	testOutput(t, b, `
+name.n
+----.---
+a....9
+a....100
+b....10
+c
+`)
+	b.SortByColumn(1, func(x, y string) bool {
+		n, _ := strconv.Atoi(x)
+		m, _ := strconv.Atoi(y)
+		return n < m
+	})
+	testOutput(t, b, `
+name.n
+----.---
+c
+a....9
+b....10
+a....100
+`)
+}
+
+func TestRowNumbers(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', RowNumbers: true, ColumnMinWidth: []int{6}})
+	b.SetHeader("name", "n²")
+	for i := 1; i <= 12; i++ {
+		b.AddRow(string(rune('a'+i-1)), Right(i*i))
+	}
+	testOutput(t, b, `
+.#.name...n²
+--.------.---
+.1.a........1
+.2.b........4
+.3.c........9
+.4.d.......16
+.5.e.......25
+.6.f.......36
+.7.g.......49
+.8.h.......64
+.9.i.......81
+10.j......100
+11.k......121
+12.l......144
+`)
+}
+
+func TestFooter(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: ' ', AlignRight: true, ColumnAlign: []Align{AlignLeft}})
+	b.SetHeader("item", "count", "cost")
+	b.AddRow("apple", 3, 1.5)
+	b.AddRow("banana", 12, 2.25)
+	b.SetFooter("total", 15)
+	testOutput(t, b, `
+item    count  cost
+------  -----  ----
+apple       3   1.5
+banana     12  2.25
+------  -----  ----
+total      15
+`)
+}
+
+func TestFooterBorderRowNumbers(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderASCII, RowNumbers: true})
+	b.AddRow("a", Right(1))
+	b.AddRow("b", Right(2))
+	b.SetFooter("sum", Right(3))
+	testOutput(t, b, `
++---+-----+---+
+| 1 | a   | 1 |
+| 2 | b   | 2 |
++---+-----+---+
+|   | sum | 3 |
++---+-----+---+
+`)
+}
+
+func TestSpanRow(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.'})
+	b.AddSpanRow(Center("fruit"))
+	b.AddRow("apple", Right(3), "red")
+	b.AddRow("kiwi", Right(12), "green")
+	b.AddSpanRow("a span row that is much too wide")
+	b.AddSpanRow(Right("end"))
+	testOutput(t, b, `
+.....fruit
+apple...3..red
+kiwi...12..green
+a span row that is much too wide
+.............end
+`)
+}
+
+func TestSpanRowBorder(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', Border: BorderUnicode, RowNumbers: true})
+	b.AddSpanRow(Center("title"))
+	b.AddRow("a", "bcd")
+	b.AddRow("e", "f")
+	testOutput(t, b, `
+┌───┬───┬─────┐
+│    title    │
+│ 1 │ a │ bcd │
+│ 2 │ e │ f   │
+└───┴───┴─────┘
+`)
+}
+
+func TestColumnSeparator(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', ColumnSeparator: "|"})
+	b.SetHeader("name", Right("count"), "note")
+	b.AddRow("apple", Right(3), "red")
+	b.AddRow("kiwi", Right(12))
+	b.AddSpanRow(Center("section"))
+	testOutput(t, b, `
+name  | count | note
+-----   -----   ----
+apple |     3 | red
+kiwi  |    12
+      section
+`)
+}
+
+func TestColumnSeparatorMultiChar(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', ColumnSeparator: " ¦ ", TrimTrailing: true})
+	b.AddRow("a", Right("bcd"), "")
+	b.AddRow("ef", Right("g"), "h")
+	testOutput(t, b, `
+a.. ¦ .bcd. ¦ 
+ef. ¦ ...g. ¦ .h
+`)
+}
+
+func TestSetCell(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("a", "b", "c")
+	b.AddRow("d", "e")
+	b.SetCell(0, 1, "much wider")
+	b.SetCell(1, 0, Right(12345))
+	testOutput(t, b, `
+a.....much wider.c
+12345.e
+`)
+	b.SetCell(0, 1, "x")
+	testOutput(t, b, `
+a.....x.c
+12345.e
+`)
+	for _, tt := range []struct {
+		row, col int
+		want     string
+	}{
+		{0, 0, "a"},
+		{0, 1, "x"},
+		{1, 0, "12345"},
+		{1, 2, ""},
+		{2, 0, ""},
+		{-1, 0, ""},
+	} {
+		if got := b.Cell(tt.row, tt.col); got != tt.want {
+			t.Errorf("Cell(%d, %d): got %q; want %q", tt.row, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestSetCellOutOfRange(t *testing.T) {
+	b := New(Options{})
+	b.AddRow("a", "b")
+	for _, rc := range [][2]int{{1, 0}, {0, 2}, {-1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("SetCell(%d, %d) did not panic", rc[0], rc[1])
+				}
+			}()
+			b.SetCell(rc[0], rc[1], "x")
+		}()
+	}
+}
+
+func TestInsertRow(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("b", 2)
+	b.AddRow("d", 4)
+	b.InsertRow(0, "a", 1)
+	b.InsertRow(2, "c", Right(3))
+	b.InsertRow(4, "eee", 5)
+	testOutput(t, b, `
+a...1
+b...2
+c...3
+d...4
+eee.5
+`)
+	for _, i := range []int{-1, 6} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("InsertRow(%d) did not panic", i)
+				}
+			}()
+			b.InsertRow(i, "x")
+		}()
+	}
+}
+
+func TestDeleteRow(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("first", 1)
+	b.AddRow("b", 2)
+	b.AddRow("middle", 3)
+	b.AddRow("c", 4)
+	b.AddRow("last", 5)
+	b.DeleteRow(2)
+	testOutput(t, b, `
+first.1
+b.....2
+c.....4
+last..5
+`)
+	b.DeleteRow(0)
+	b.DeleteRow(2)
+	testOutput(t, b, `
+b.2
+c.4
+`)
+	for _, i := range []int{-1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("DeleteRow(%d) did not panic", i)
+				}
+			}()
+			b.DeleteRow(i)
+		}()
+	}
+}
+
+func TestPadRune(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', PadRune: '·'})
+	b.SetHeader("name", Right("count"))
+	b.AddRow("apple", Right(3))
+	b.AddRow(Center("fig"), Right(12))
+	testOutput(t, b, `
+name···count
+-----··-----
+apple······3
+·fig······12
+`)
+}
+
+func TestPadRuneDoubleWidth(t *testing.T) {
+	b := New(Options{Padding: 2, PadRune: '＊', EastAsianWidth: true})
+	b.AddRow("abcde", "x")
+	b.AddRow("ab", Right("yz"))
+	b.AddRow("abcd", "w")
+	testOutput(t, b, `
+abcde＊x
+ab＊ ＊yz
+abcd ＊w
+`)
+}
+
+func TestColumnPadChar(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: ' ', ColumnPadChar: []rune{'.'}})
+	b.AddRow("Introduction", Right(1), "x")
+	b.AddRow("Setup", Right(12), "y")
+	b.AddRow(Right("End"), Right(123), "z")
+	testOutput(t, b, `
+Introduction   1 x
+Setup.......  12 y
+.........End 123 z
+`)
+	b = New(Options{Padding: 1, PadChar: ' ', ColumnPadChar: []rune{0, '.'}})
+	b.AddRow("Introduction", Right(1))
+	b.AddRow("Setup", Right(12))
+	testOutput(t, b, `
+Introduction..1
+Setup       .12
+`)
+}
+
+func TestInterpretControls(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', InterpretControls: true})
+	b.AddRow("abc\rXY", "x")
+	b.AddRow("abcd", "y")
+	testOutput(t, b, "abc\rXY..x\n"+
+		"abcd.y\n")
+}
+
+func TestHyperlink(t *testing.T) {
+	link := "\x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\"
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow(link, "x")
+	b.AddRow("example", "y")
+	b.AddRow(Right(link), "z")
+	testOutput(t, b, link+"....x\n"+
+		"example.y\n"+
+		"..."+link+".z\n")
+}
+
+func TestWidthFunc(t *testing.T) {
+	byteLen := func(s string) int { return len(s) }
+	b := New(Options{Padding: 1, PadChar: '.', WidthFunc: byteLen})
+	b.AddRow("é", "x")
+	b.AddRow("ab", Right("☃"))
+	b.AddRow("c", "y")
+	testOutput(t, b, `
+é.x
+ab.☃
+c..y
+`)
+
+	b = New(Options{MaxWidth: 5, WidthFunc: byteLen})
+	b.AddRow("ééé")
+	testOutput(t, b, "é…\n")
+}
+
+func TestLines(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", "count")
+	b.AddRow("a", Right(1))
+	b.AddRow("bb\ncc", Right(22))
+	b.AddSpanRow("span")
+	var got []string
+	for line := range b.Lines() {
+		got = append(got, line)
+	}
+	want := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Lines (-got, +want):\n%s", diff)
+	}
+
+	got = got[:0]
+	for line := range b.Lines() {
+		got = append(got, line)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if diff := cmp.Diff(got, want[:2]); diff != "" {
+		t.Errorf("Lines with break (-got, +want):\n%s", diff)
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		opts  Options
+		build func(b *Buffer)
+		w, h  int
+	}{
+		{"empty", Options{}, func(b *Buffer) {}, 0, 0},
+		{
+			"ragged",
+			Options{Padding: 2, MinWidth: 3},
+			func(b *Buffer) {
+				b.AddRow("a", "bbbbb", "ccc")
+				b.AddRow("dd")
+				b.AddRow("e", "f")
+			},
+			3 + 2 + 5 + 2 + 3, 3,
+		},
+		{
+			"multiline",
+			Options{Padding: 1, ColumnSeparator: "|"},
+			func(b *Buffer) {
+				b.SetHeader("name", "value")
+				b.AddRow("a\nb\nc", "x")
+				b.AddRule()
+				b.AddSpanRow("one\ntwo")
+				b.SetFooter("total", "y\nz")
+			},
+			5 + 3 + 5, 2 + 3 + 1 + 2 + 3,
+		},
+		{
+			"border",
+			Options{Padding: 1, Border: BorderUnicode},
+			func(b *Buffer) {
+				b.AddRow("a", "bb")
+			},
+			1 + 1 + 1 + 1 + 1 + 1 + 2 + 1 + 1, 3,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.opts)
+			tt.build(b)
+			w, h := b.Size()
+			if w != tt.w || h != tt.h {
+				t.Errorf("Size() = %d, %d; want %d, %d", w, h, tt.w, tt.h)
+			}
+			var maxWidth, lines int
+			for line := range b.Lines() {
+				lines++
+				if n := StringWidth(line); n > maxWidth {
+					maxWidth = n
+				}
+			}
+			if maxWidth != w || lines != h {
+				t.Errorf("Size() = %d, %d, but the table is %d, %d", w, h, maxWidth, lines)
+			}
+		})
+	}
+}
+
+func TestWriteToFunc(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', Border: BorderASCII})
+	b.SetHeader("name", "count")
+	b.AddRow("a", Right(1))
+	b.AddRow("bb\ncc", Right(22))
+	var buf bytes.Buffer
+	var indexes []int
+	n, err := b.WriteToFunc(func(i int, line []byte) error {
+		indexes = append(indexes, i)
+		buf.Write(line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := b.String()
+	if got := buf.String(); got != want {
+		t.Errorf("WriteToFunc: got\n%s\nwant\n%s", got, want)
+	}
+	if int(n) != len(want) {
+		t.Errorf("WriteToFunc returned %d; want %d", n, len(want))
+	}
+	if diff := cmp.Diff(indexes, []int{0, 1, 2, 3, 4, 5, 6}); diff != "" {
+		t.Errorf("line indexes (-got, +want):\n%s", diff)
+	}
+
+	errStop := errors.New("stop")
+	var lines int
+	n, err = b.WriteToFunc(func(i int, line []byte) error {
+		if i == 2 {
+			return errStop
+		}
+		lines++
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("got error %v; want %v", err, errStop)
+	}
+	first := strings.SplitAfter(want, "\n")
+	if lines != 2 || int(n) != len(first[0])+len(first[1]) {
+		t.Errorf("after error: got %d lines and n=%d", lines, n)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', RowNumbers: true})
+	b.SetHeader("name", "count", "note")
+	b.AddRow("a", Right(1), Center("x"))
+	b.AddRow("bbbb", Right(22))
+	b.AddRule()
+	b.AddSpanRow(Right("span"))
+	b.AddRow("c\nd", Decimal(3.5), "yyy")
+	got := b.Grid()
+	want := [][]string{
+		{"#", "name", "count", "note"},
+		{"1", "a...", "....1", ".x.."},
+		{"2", "bbbb", "...22"},
+		{"................span"},
+		{"3", "c...\nd...", "..3.5", "yyy."},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Grid (-got, +want):\n%s", diff)
+	}
+	widths := []int{1, 4, 5, 4}
+	for i, row := range got {
+		if len(row) == 1 {
+			continue
+		}
+		for j, c := range row {
+			for _, line := range strings.Split(c, "\n") {
+				if w := StringWidth(line); w != widths[j] {
+					t.Errorf("row %d, column %d: %q has width %d; want %d", i, j, line, w, widths[j])
+				}
+			}
+		}
+	}
+}
+
+func TestAddRowAfterWrite(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.'})
+	b.AddRow("this", "is", Right("a"), "test")
+	b.AddRow(1, 2, Right(true), false)
+	testOutput(t, b, `
+this..is.....a..test
+1.....2...true..false
+`)
+	b.AddRow(2, Right("x"), 1839834, "yes")
+	testOutput(t, b, `
+this..is........a..test
+1.....2......true..false
+2......x..1839834..yes
+`)
+}
+
+func TestWriteToTwice(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("a", "bc")
+	b.AddRow("def", "g")
+	want := `
+a...bc
+def.g
+`
+	testOutput(t, b, want)
+	testOutput(t, b, want)
+}
+
+func TestReset(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("x", "y")
+	b.AddRow("long value", "z")
+	b.Reset()
+	testOutput(t, b, "")
+	b.AddRow("a", "b")
+	testOutput(t, b, `
+a.b
+`)
+}
+
+func TestNumRowsColumns(t *testing.T) {
+	b := New(Options{})
+	check := func(rows, cols int) {
+		t.Helper()
+		if got := b.NumRows(); got != rows {
+			t.Errorf("NumRows: got %d; want %d", got, rows)
+		}
+		if got := b.NumColumns(); got != cols {
+			t.Errorf("NumColumns: got %d; want %d", got, cols)
+		}
+	}
+	check(0, 0)
+	b.AddRow("a", "b")
+	b.AddRow("a", "b", "c", "d")
+	b.AddRow("a")
+	check(3, 4)
+	b.SetHeader(1, 2, 3, 4, 5)
+	check(3, 5)
+	b.SetFooter(1, 2, 3, 4, 5, 6)
+	check(3, 6)
+	b.Reset()
+	check(0, 0)
+}
+
+func TestSetColumnWidths(t *testing.T) {
+	widths := []int{6, 4}
+	b0 := New(Options{Padding: 1, PadChar: '.', MinWidth: 5})
+	b0.SetColumnWidths(widths)
+	b0.AddRow("a", Right(1), "x")
+	b0.AddRow("bb", Right(22), "y")
+	b1 := New(Options{Padding: 1, PadChar: '.', MinWidth: 5})
+	b1.SetColumnWidths(widths)
+	b1.AddRow("longer", Right(333), "z")
+	b1.AddRow("overflowing", Right(4444), "w")
+	testOutput(t, b0, `
+a.........1.x
+bb.......22.y
+`)
+	testOutput(t, b1, `
+longer..333.z
+overflowing.4444.w
+`)
+
+	b1.opts.TruncateFixedWidths = true
+	b1.SetColumnWidths([]int{-1, 4})
+	testOutput(t, b1, `
+longer.......333.z
+overflowing.4444.w
+`)
+	widths[0] = 3
+	b1.SetColumnWidths(widths)
+	testOutput(t, b1, `
+lo…..333.z
+ov….4444.w
+`)
+	b1.SetColumnWidths(nil)
+	testOutput(t, b1, `
+longer........333.z
+overflowing..4444.w
+`)
+}
+
+func TestStickyWidths(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', StickyWidths: true})
+	b.AddRow("a long name", Right(12345), "x")
+	testOutput(t, b, `
+a long name.12345.x
+`)
+	b.Reset()
+	b.AddRow("short", Right(1), "y")
+	testOutput(t, b, `
+short...........1.y
+`)
+	b.AddRow("an even longer name", Right(2))
+	testOutput(t, b, `
+short...................1.y
+an even longer name.....2
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.', StickyWidths: true})
+	b.AddRow("a long name", Right(12345), "x")
+	b.Size()
+	b.Grid()
+	b.Layout()
+	b.Reset()
+	b.AddRow("short", Right(1), "y")
+	testOutput(t, b, `
+short.1.y
+`)
+
+	b = New(Options{Padding: 1, PadChar: '.'})
+	b.AddRow("a long name", Right(12345), "x")
+	_ = b.String()
+	b.Reset()
+	b.AddRow("short", Right(1), "y")
+	testOutput(t, b, `
+short.1.y
+`)
+}
+
+func TestClone(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.'})
+	b.SetHeader("name", "n")
+	b.AddRow("a", 1)
+	b.AddRow("b", 2)
+	b.SetFooter("total", 3)
+	want := b.String()
+
+	c := b.Clone()
+	if got := c.String(); got != want {
+		t.Fatalf("clone: got\n%s\nwant\n%s", got, want)
+	}
+	c.SetCell(0, 0, "changed")
+	c.AddRow("c", 4)
+	c.SetHeader("x")
+	if got := b.String(); got != want {
+		t.Errorf("after changing clone: got\n%s\nwant\n%s", got, want)
+	}
+	c.Reset()
+	if got := b.String(); got != want {
+		t.Errorf("after resetting clone: got\n%s\nwant\n%s", got, want)
+	}
+
+	c = b.Clone()
+	b.SetCell(1, 1, 22)
+	if got := c.String(); got != want {
+		t.Errorf("after changing original: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	b := New(Options{Padding: 1, Concurrent: true})
+	b.SetHeader("worker", "row")
+	const workers, rows = 20, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rows; j++ {
+				b.AddRow(i, j)
+				if j%10 == 0 {
+					_ = b.String()
+					var buf bytes.Buffer
+					b.WriteHTML(&buf)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got, want := b.NumRows(), workers*rows; got != want {
+		t.Errorf("NumRows() = %d; want %d", got, want)
+	}
+	if got, want := strings.Count(b.String(), "\n"), workers*rows+2; got != want {
+		t.Errorf("table has %d lines; want %d", got, want)
+	}
+}
+
+func TestBytesString(t *testing.T) {
+	b := New(Options{Padding: 1, PadChar: '.', Border: BorderASCII})
+	b.SetHeader("a", "b")
+	b.AddRow("cd", Right("e"))
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := buf.String()
+	if got := string(b.Bytes()); got != want {
+		t.Errorf("Bytes: got %q; want %q", got, want)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("String: got %q; want %q", got, want)
+	}
+	if got := New(Options{}).Bytes(); len(got) != 0 {
+		t.Errorf("Bytes for empty buffer: got %q", got)
+	}
+}
+
 func testOutput(t *testing.T, w *Buffer, want string) {
 	t.Helper()
 	want = strings.TrimPrefix(want, "\n")
@@ -0,0 +1,22 @@
+package tabular
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// terminalWidth returns the width of the terminal that w writes to, if it is
+// one. It is a variable so that tests can replace it.
+var terminalWidth = func(w io.Writer) (width int, ok bool) {
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0, false
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return 0, false
+	}
+	return width, true
+}
@@ -0,0 +1,53 @@
+package tabular
+
+import "io"
+
+// WriteTSV writes the buffered rows as lines of tab-separated fields,
+// starting with the header and ending with the footer, if they are set. Like
+// WriteCSV, each line contains the cells of a row as formatted by AddRow,
+// without any alignment, truncation, or wrapping, so lines may have
+// differing numbers of fields, and rules added by AddRule and blank rows
+// added by AddBlankRow are omitted.
+// Cells are written as is, so cells containing tabs or newlines produce
+// extra fields or lines.
+func (b *Buffer) WriteTSV(w io.Writer) (int64, error) {
+	defer b.lock()()
+	var line []byte
+	var written int64
+	write := func(row []cell) error {
+		line = line[:0]
+		for i, c := range row {
+			if i > 0 {
+				line = append(line, '\t')
+			}
+			if b.opts.StripCSIForCSV {
+				line = append(line, b.opts.stripEscapes(c.s)...)
+			} else {
+				line = append(line, c.s...)
+			}
+		}
+		line = append(line, '\n')
+		n, err := w.Write(line)
+		written += int64(n)
+		return err
+	}
+	if b.header != nil {
+		if err := write(b.header); err != nil {
+			return written, err
+		}
+	}
+	for _, r := range b.rows {
+		if r.kind == rowRule || r.kind == rowBlank {
+			continue
+		}
+		if err := write(r.cells); err != nil {
+			return written, err
+		}
+	}
+	if b.footer != nil {
+		if err := write(b.footer); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
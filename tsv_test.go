@@ -0,0 +1,59 @@
+package tabular
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTSV(t *testing.T) {
+	b := New(Options{Padding: 2, PadChar: '.', MaxWidth: 4})
+	b.SetHeader("name", Right("count"), "note")
+	b.AddRow("a b", 1, "liberté")
+	b.AddRow(Center("x"), Right(22))
+	b.AddRule()
+	b.AddRow("\x1b[31mred\x1b[0m", "", "", "extra")
+	b.AddSpanRow("span")
+	b.SetFooter("total", 23)
+	testTSV(t, b, "name\tcount\tnote\n"+
+		"a b\t1\tliberté\n"+
+		"x\t22\n"+
+		"\x1b[31mred\x1b[0m\t\t\textra\n"+
+		"span\n"+
+		"total\t23\n")
+
+	var buf bytes.Buffer
+	b.WriteTSV(&buf)
+	for i, want := range []int{3, 3, 2, 4, 1, 2} {
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(strings.Split(strings.TrimSuffix(line, "\n"), "\t")); got != want {
+			t.Errorf("line %d has %d fields; want %d", i, got, want)
+		}
+	}
+}
+
+func TestTSVStripCSI(t *testing.T) {
+	b := New(Options{StripCSIForCSV: true})
+	b.AddRow("\x1b[1mbold\x1b[0m", "plain")
+	testTSV(t, b, "bold\tplain\n")
+}
+
+func testTSV(t *testing.T, b *Buffer, want string) {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := b.WriteTSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTSV returned n=%d; wrote %d bytes", n, buf.Len())
+	}
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("wrong output (-got, +want):\n%s", diff)
+	}
+}
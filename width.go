@@ -0,0 +1,489 @@
+package tabular
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// escRegexp matches ANSI CSI escape sequences, such as those used to set
+// terminal colors, and OSC sequences (terminated by BEL or ST), such as
+// OSC 8 hyperlinks.
+var escRegexp = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// escapes returns the bounds of the ANSI escape sequences in s, as by
+// escRegexp.FindAllStringIndex, or if o.StrictCSI is set, only those that
+// are OSC or SGR sequences.
+func (o *Options) escapes(s string) [][]int {
+	if strings.IndexByte(s, '\x1b') < 0 {
+		return nil
+	}
+	if !o.StrictCSI {
+		return escRegexp.FindAllStringIndex(s, -1)
+	}
+	var esc [][]int
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\x1b' || i+1 == len(s) {
+			continue
+		}
+		var end int
+		switch s[i+1] {
+		case '[':
+			end = sgrEnd(s[i+2:])
+		case ']':
+			if loc := escRegexp.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+				end = loc[1] - 2
+			}
+		}
+		if end > 0 {
+			esc = append(esc, []int{i, i + 2 + end})
+			i += 1 + end
+		}
+	}
+	return esc
+}
+
+// sgrEnd returns the length of the parameters and final 'm' of an SGR
+// sequence at the start of s (following the "\x1b["), or 0 if there isn't
+// one.
+func sgrEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9' || c == ';' || c == ':':
+		case c == 'm':
+			return i + 1
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// stripEscapes removes the ANSI escape sequences from s that are measured as
+// invisible (see Options.StrictCSI).
+func (o *Options) stripEscapes(s string) string {
+	esc := o.escapes(s)
+	if esc == nil {
+		return s
+	}
+	var sb strings.Builder
+	var i int
+	for _, loc := range esc {
+		sb.WriteString(s[i:loc[0]])
+		i = loc[1]
+	}
+	sb.WriteString(s[i:])
+	return sb.String()
+}
+
+// segments splits s into the units used to measure its width: ANSI CSI and
+// OSC sequences (unless o.CountCSI is set), which have width 0, and code
+// points, which have width 1 (or 2; see Options.EastAsianWidth and
+// Options.WideChars). If
+// o.GraphemeWidth is set, code points are grouped into grapheme clusters,
+// each of which has the width of its first code point, or 2 if it is
+// displayed as an emoji. It calls fn with the
+// bounds s[i:j] and the width of each unit in order. If o.WidthFunc is set,
+// it measures each unit instead.
+func (o *Options) segments(s string, fn func(i, j, w int)) {
+	var esc [][]int
+	if !o.CountCSI {
+		esc = o.escapes(s)
+	}
+	for i := 0; i < len(s); {
+		if len(esc) > 0 && esc[0][0] == i {
+			w := 0
+			if o.WidthFunc != nil {
+				w = o.WidthFunc(s[i:esc[0][1]])
+			}
+			fn(i, esc[0][1], w)
+			i = esc[0][1]
+			esc = esc[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		j := i + size
+		if o.GraphemeWidth {
+			j = clusterEnd(s, r, j)
+		}
+		if o.WidthFunc != nil {
+			fn(i, j, o.WidthFunc(s[i:j]))
+		} else if w := o.runeWidth(r); w < 2 && o.GraphemeWidth && isEmoji(s[i:j], r) {
+			fn(i, j, 2)
+		} else {
+			fn(i, j, w)
+		}
+		i = j
+	}
+}
+
+// clusterEnd returns the end of the grapheme cluster that begins with r and
+// continues at s[j:]. It handles the common cases of combining marks,
+// variation selectors, emoji modifiers and tag sequences, ZWJ sequences, and
+// regional indicator pairs (flags), but is not a full implementation of
+// Unicode text segmentation.
+func clusterEnd(s string, r rune, j int) int {
+	const zwj = '\u200d'
+	pairRI := isRegionalIndicator(r)
+	for j < len(s) {
+		next, size := utf8.DecodeRuneInString(s[j:])
+		switch {
+		case pairRI && isRegionalIndicator(next):
+			j += size
+		case next == zwj:
+			j += size
+			if j < len(s) {
+				_, size = utf8.DecodeRuneInString(s[j:])
+				j += size
+			}
+		case isGraphemeExtend(next):
+			j += size
+		default:
+			return j
+		}
+		pairRI = false
+	}
+	return j
+}
+
+// isEmoji reports whether the grapheme cluster c, which begins with r, is
+// displayed as an emoji, which terminals draw 2 columns wide: a flag, a
+// sequence with the emoji presentation selector U+FE0F (such as a keycap),
+// or a sequence that begins with a wide pictograph.
+func isEmoji(c string, r rune) bool {
+	if isRegionalIndicator(r) {
+		return len(c) > utf8.RuneLen(r)
+	}
+	if strings.ContainsRune(c, '\ufe0f') {
+		return true
+	}
+	if width.LookupRune(r).Kind() != width.EastAsianWide {
+		return false
+	}
+	return r >= 0x1f000 && r <= 0x1faff || r >= 0x2300 && r <= 0x2bff
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r >= 0x1f3fb && r <= 0x1f3ff: // emoji skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // tags
+		return true
+	}
+	// Mn includes the variation selectors.
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+func (o *Options) runeWidth(r rune) int {
+	if o.SanitizeControls && (unicode.Is(unicode.Cc, r) || unicode.Is(unicode.Cf, r)) {
+		return 0
+	}
+	if o.EastAsianWidth || o.WideChars {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			return 2
+		case width.EastAsianAmbiguous:
+			if o.EastAsianWidth {
+				return 2
+			}
+		}
+	}
+	return 1
+}
+
+// StringWidth returns the visible width of s as measured by a Buffer with
+// the default Options: the number of code points in s, not counting ANSI CSI
+// and OSC sequences.
+func StringWidth(s string) int {
+	return new(Options).cellWidth(s)
+}
+
+// StringWidth returns the visible width of s as measured by a Buffer
+// created with o. Only the options that affect measurement, such as
+// CountCSI and EastAsianWidth, are relevant.
+func (o *Options) StringWidth(s string) int {
+	return o.cellWidth(s)
+}
+
+// cellWidth returns the visible width of s.
+func (o *Options) cellWidth(s string) int {
+	if o.WidthFunc != nil {
+		return o.WidthFunc(s)
+	}
+	if o.InterpretControls && strings.ContainsAny(s, "\r\b") {
+		return o.controlWidth(s)
+	}
+	var n int
+	o.segments(s, func(_, _, w int) { n += w })
+	return n
+}
+
+// controlWidth returns the visible width of s when it is written to a
+// terminal that moves the cursor back to the first column for a carriage
+// return and back by one column for a backspace: the furthest column that
+// the text reaches.
+func (o *Options) controlWidth(s string) int {
+	var col, max int
+	o.segments(s, func(i, j, w int) {
+		switch s[i:j] {
+		case "\r":
+			col = 0
+		case "\b":
+			if col > 0 {
+				col--
+			}
+		default:
+			col += w
+			if col > max {
+				max = col
+			}
+		}
+	})
+	return max
+}
+
+// justify returns s with the runs of spaces between its words widened,
+// the earlier ones first, so that it has a visible width of w. If s is
+// already at least that wide or has no spaces between words, it is returned
+// unchanged.
+func (o *Options) justify(s string, w int) string {
+	extra := w - o.cellWidth(s)
+	if extra <= 0 {
+		return s
+	}
+	// Find the runs of spaces that follow a word and precede another.
+	start := len(s) - len(strings.TrimLeft(s, " "))
+	end := len(strings.TrimRight(s, " "))
+	var gaps []int
+	for i := start; i < end; i++ {
+		if s[i] == ' ' && s[i-1] != ' ' {
+			gaps = append(gaps, i)
+		}
+	}
+	if len(gaps) == 0 {
+		return s
+	}
+	var sb strings.Builder
+	prev := 0
+	for k, i := range gaps {
+		sb.WriteString(s[prev:i])
+		n := extra / len(gaps)
+		if k < extra%len(gaps) {
+			n++
+		}
+		sb.WriteString(strings.Repeat(" ", n))
+		prev = i
+	}
+	sb.WriteString(s[prev:])
+	return sb.String()
+}
+
+const ellipsis = "…"
+
+// A TruncateMode specifies which part of a cell is replaced by an ellipsis
+// when it is truncated.
+type TruncateMode int
+
+// These are the possible truncation modes.
+const (
+	TruncateEnd    TruncateMode = iota // keep the start of the text
+	TruncateStart                      // keep the end of the text
+	TruncateMiddle                     // keep the start and end of the text
+)
+
+// truncate shortens s to have a visible width of at most w by replacing
+// part of the text, as selected by o.TruncateMode, with an ellipsis.
+// Zero-width segments (such as CSI sequences) in the removed portion of s
+// are retained, following the ellipsis, so that, for instance, a trailing
+// color reset still takes effect.
+func (o *Options) truncate(s string, w int) string {
+	if o.cellWidth(s) <= w {
+		return s
+	}
+	keep := w - o.cellWidth(ellipsis)
+	if keep < 0 {
+		return ""
+	}
+	var headWidth, tailWidth int
+	switch o.TruncateMode {
+	case TruncateStart:
+		tailWidth = keep
+	case TruncateMiddle:
+		headWidth = keep - keep/2
+		tailWidth = keep / 2
+	default:
+		headWidth = keep
+	}
+	type segment struct{ i, w int }
+	var segs []segment
+	o.segments(s, func(i, _, sw int) { segs = append(segs, segment{i, sw}) })
+	start := func(k int) int {
+		if k == len(segs) {
+			return len(s)
+		}
+		return segs[k].i
+	}
+	// The head is segs[:h] and the tail is segs[t:].
+	h, n := 0, 0
+	for h < len(segs) && n+segs[h].w <= headWidth {
+		n += segs[h].w
+		h++
+	}
+	t, n := len(segs), 0
+	for t > h && n+segs[t-1].w <= tailWidth {
+		n += segs[t-1].w
+		t--
+	}
+	var sb strings.Builder
+	sb.WriteString(s[:start(h)])
+	sb.WriteString(ellipsis)
+	for k := h; k < t; k++ {
+		if segs[k].w == 0 {
+			sb.WriteString(s[segs[k].i:start(k+1)])
+		}
+	}
+	sb.WriteString(s[start(t):])
+	return sb.String()
+}
+
+// A WrapMode specifies where the lines of a wrapped cell are broken.
+type WrapMode int
+
+// These are the possible wrapping modes.
+const (
+	WrapRune WrapMode = iota // break anywhere, filling each line
+	WrapWord                 // break at spaces, where possible
+)
+
+// wrap splits s into lines with a visible width of at most w (which must be
+// positive), breaking between segments. Zero-width segments stay with the
+// text that precedes them, and SGR styles are carried onto later lines
+// (see carryStyles). If o.WrapMode is WrapWord, see wrapWords.
+func (o *Options) wrap(s string, w int) []string {
+	if o.cellWidth(s) <= w {
+		return []string{s}
+	}
+	var lines []string
+	if o.WrapMode == WrapWord {
+		lines = o.wrapWords(s, w)
+	} else {
+		var start, n int
+		o.segments(s, func(i, _, sw int) {
+			if sw > 0 && n > 0 && n+sw > w {
+				lines = append(lines, s[start:i])
+				start = i
+				n = 0
+			}
+			n += sw
+		})
+		lines = append(lines, s[start:])
+	}
+	if !o.CountCSI && strings.IndexByte(s, '\x1b') >= 0 {
+		carryStyles(lines)
+	}
+	return lines
+}
+
+// carryStyles makes each of lines, the lines of a wrapped cell, begin with
+// the SGR sequences in effect at its start and, except for the last line,
+// end with a reset if any are in effect at its end. Then a style set for
+// the cell applies to each of its lines but not to the cells that follow
+// them.
+func carryStyles(lines []string) {
+	var active string
+	for k, line := range lines {
+		prefix := active
+		for i := 0; i+1 < len(line); i++ {
+			if line[i] != '\x1b' || line[i+1] != '[' {
+				continue
+			}
+			n := sgrEnd(line[i+2:])
+			if n == 0 {
+				continue
+			}
+			if seq := line[i : i+2+n]; seq == "\x1b[m" || seq == "\x1b[0m" {
+				active = ""
+			} else {
+				active += seq
+			}
+			i += 1 + n
+		}
+		line = prefix + line
+		if active != "" && k < len(lines)-1 {
+			line += "\x1b[0m"
+		}
+		lines[k] = line
+	}
+}
+
+// wrapWords is like wrap, but breaks lines at the last space that fits,
+// falling back to breaking between segments for a word wider than w. The
+// spaces on either side of each break are removed.
+func (o *Options) wrapWords(s string, w int) []string {
+	var lines []string
+	var start, n int
+	// space is the index in s of the last space in the current line, or
+	// -1, and spaceEnd is the width of the line up to and including it.
+	space, spaceEnd := -1, 0
+	o.segments(s, func(i, j, sw int) {
+		isSpace := s[i:j] == " "
+		if isSpace && i == start && len(lines) > 0 {
+			start = j // a space at the start of a wrapped line
+			return
+		}
+		if sw > 0 && n > 0 && n+sw > w {
+			switch {
+			case isSpace:
+				lines = append(lines, s[start:i])
+				start, n, space = j, 0, -1
+				return
+			case space >= 0:
+				lines = append(lines, s[start:space])
+				start, n, space = space+1, n-spaceEnd, -1
+			}
+			if n > 0 && n+sw > w {
+				lines = append(lines, s[start:i])
+				start, n = i, 0
+			}
+		}
+		n += sw
+		if isSpace {
+			space, spaceEnd = i, n
+		}
+	})
+	lines = append(lines, s[start:])
+	for i := range lines[:len(lines)-1] {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return lines
+}
+
+// expandTabs replaces each tab in s with enough spaces to reach the next
+// multiple of w, measured in visible width.
+func (o *Options) expandTabs(s string, w int) string {
+	if strings.IndexByte(s, '\t') < 0 {
+		return s
+	}
+	var out strings.Builder
+	var col int
+	for {
+		i := strings.IndexByte(s, '\t')
+		if i < 0 {
+			out.WriteString(s)
+			return out.String()
+		}
+		out.WriteString(s[:i])
+		col += o.cellWidth(s[:i])
+		n := w - col%w
+		out.WriteString(strings.Repeat(" ", n))
+		col += n
+		s = s[i+1:]
+	}
+}
@@ -0,0 +1,330 @@
+package tabular
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCellWidth(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"liberté", 7},
+		{"世界", 2},
+		{"\x1b[31mred\x1b[0m", 3},
+		{"\x1b[1;38;5;208mx\x1b[m", 1},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", 4},
+		{"\x1b]8;id=1;https://example.com\alink\x1b]8;;\a", 4},
+		{"\x1b]0;title\a", 0},
+	} {
+		if got := new(Options).cellWidth(tt.s); got != tt.want {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"⌘", 1},
+		{"égalité", 7},
+		{"\x1b[31mred\x1b[0m", 3},
+	} {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q): got %d; want %d", tt.s, got, tt.want)
+		}
+	}
+	o := &Options{CountCSI: true, EastAsianWidth: true}
+	if got, want := o.StringWidth("\x1b[0m世界"), 8; got != want {
+		t.Errorf("Options.StringWidth: got %d; want %d", got, want)
+	}
+}
+
+func TestCellWidthCountCSI(t *testing.T) {
+	const s = "\x1b[31mred\x1b[0m"
+	if got, want := new(Options).cellWidth(s), 3; got != want {
+		t.Errorf("cellWidth(%q): got %d; want %d", s, got, want)
+	}
+	o := &Options{CountCSI: true}
+	if got, want := o.cellWidth(s), 12; got != want {
+		t.Errorf("with CountCSI, cellWidth(%q): got %d; want %d", s, got, want)
+	}
+}
+
+func TestCellWidthStrictCSI(t *testing.T) {
+	for _, tt := range []struct {
+		s             string
+		loose, strict int
+	}{
+		{"\x1b[1;31mred\x1b[0m", 3, 3},
+		{"\x1b[38:5:196mred\x1b[m", 3, 3},
+		{"\x1b]8;;http://example.com\x07link\x1b]8;;\x07", 4, 4},
+		{"red\x1b[", 5, 5},
+		{"\x1b[abc", 2, 5},
+		{"\x1b[2Jclear", 5, 9},
+	} {
+		if got := new(Options).cellWidth(tt.s); got != tt.loose {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.loose)
+		}
+		o := &Options{StrictCSI: true}
+		if got := o.cellWidth(tt.s); got != tt.strict {
+			t.Errorf("cellWidth(%q) with StrictCSI: got %d; want %d", tt.s, got, tt.strict)
+		}
+	}
+	o := &Options{StrictCSI: true}
+	if got, want := o.stripEscapes("\x1b[1mbold\x1b[0m \x1b[2J\x1b["), "bold \x1b[2J\x1b["; got != want {
+		t.Errorf("stripEscapes: got %q; want %q", got, want)
+	}
+}
+
+func TestCellWidthInterpretControls(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"abc\rXY", 3},
+		{"ab\rwxyz", 4},
+		{"abc\b\b", 3},
+		{"abc\b\bX", 3},
+		{"a\b\b\bXYZ", 3},
+		{"\x1b[1mab\x1b[0m\rc", 2},
+	} {
+		o := &Options{InterpretControls: true}
+		if got := o.cellWidth(tt.s); got != tt.want {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.want)
+		}
+	}
+	if got, want := new(Options).cellWidth("abc\rXY"), 6; got != want {
+		t.Errorf("cellWidth without InterpretControls: got %d; want %d", got, want)
+	}
+}
+
+func TestCellWidthSanitizeControls(t *testing.T) {
+	for _, tt := range []struct {
+		s         string
+		raw, want int
+	}{
+		{"zero\u200bwidth", 10, 9},
+		{"soft\u00adhyphen", 11, 10},
+		{"a\u200db", 3, 2},
+		{"bell\x07\x7f", 6, 4},
+		{"c1\u0085", 3, 2},
+		{"\ufeffbom", 4, 3},
+		{"\x1b[31mred\x1b[0m", 3, 3},
+	} {
+		if got := new(Options).cellWidth(tt.s); got != tt.raw {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.raw)
+		}
+		o := &Options{SanitizeControls: true}
+		if got := o.cellWidth(tt.s); got != tt.want {
+			t.Errorf("cellWidth(%q) with SanitizeControls: got %d; want %d", tt.s, got, tt.want)
+		}
+	}
+	o := &Options{SanitizeControls: true, InterpretControls: true}
+	if got, want := o.cellWidth("abc\rx\u200b"), 3; got != want {
+		t.Errorf("cellWidth with InterpretControls: got %d; want %d", got, want)
+	}
+}
+
+func TestCellWidthEastAsian(t *testing.T) {
+	for _, tt := range []struct {
+		s         string
+		want      int
+		wantEastA int
+		wantWide  int
+	}{
+		{"abc", 3, 3, 3},
+		{"→", 1, 2, 1},
+		{"─│", 2, 4, 2},
+		{"世界", 2, 4, 4},
+		{"ｘ", 1, 2, 2},
+		{"ｶ", 1, 1, 1},
+		{"😀", 1, 2, 2},
+	} {
+		if got := new(Options).cellWidth(tt.s); got != tt.want {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.want)
+		}
+		o := &Options{EastAsianWidth: true}
+		if got := o.cellWidth(tt.s); got != tt.wantEastA {
+			t.Errorf("with EastAsianWidth, cellWidth(%q): got %d; want %d", tt.s, got, tt.wantEastA)
+		}
+		o = &Options{WideChars: true}
+		if got := o.cellWidth(tt.s); got != tt.wantWide {
+			t.Errorf("with WideChars, cellWidth(%q): got %d; want %d", tt.s, got, tt.wantWide)
+		}
+	}
+}
+
+func TestCellWidthGrapheme(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		want     int
+		wantGrph int
+	}{
+		{"abc", 3, 3},
+		{"👨\u200d👩\u200d👧", 5, 2},                 // family
+		{"🇺🇸🇫🇷", 4, 4},                            // flags
+		{"🇺🇸🇫", 3, 3},                             // flag and a lone regional indicator
+		{"👍🏽", 2, 2},                              // skin tone modifier
+		{"1\ufe0f\u20e3", 3, 2},                   // keycap
+		{"cafe\u0301", 5, 4},                      // combining accent
+		{"🏴\U000e0067\U000e0062\U000e007f", 4, 2}, // tag sequence
+	} {
+		if got := new(Options).cellWidth(tt.s); got != tt.want {
+			t.Errorf("cellWidth(%q): got %d; want %d", tt.s, got, tt.want)
+		}
+		o := &Options{GraphemeWidth: true}
+		if got := o.cellWidth(tt.s); got != tt.wantGrph {
+			t.Errorf("with GraphemeWidth, cellWidth(%q): got %d; want %d", tt.s, got, tt.wantGrph)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"abc", 3, "abc"},
+		{"abcd", 3, "ab…"},
+		{"abcd", 1, "…"},
+		{"abcd", 0, ""},
+		{"世界你好", 3, "世界…"},
+		{"👍🏽👍🏽", 3, "👍🏽…"},
+		{"\x1b[31mlongred\x1b[0m", 4, "\x1b[31mlon…\x1b[0m"},
+		{"\x1b[31mred\x1b[0m", 3, "\x1b[31mred\x1b[0m"},
+		{"ab\x1b[1mcd\x1b[0mef", 4, "ab\x1b[1mc…\x1b[0m"},
+	} {
+		got := new(Options).truncate(tt.s, tt.w)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d): got %q; want %q", tt.s, tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateMode(t *testing.T) {
+	const path = "/very/long/path/to/file.txt"
+	for _, tt := range []struct {
+		mode TruncateMode
+		s    string
+		w    int
+		want string
+	}{
+		{TruncateEnd, path, 12, "/very/long/…"},
+		{TruncateStart, path, 12, "…to/file.txt"},
+		{TruncateMiddle, path, 12, "/very/…e.txt"},
+		{TruncateMiddle, path, 13, "/very/…le.txt"},
+		{TruncateMiddle, path, 1, "…"},
+		{TruncateMiddle, "世界你好世界", 5, "世界…世界"},
+		{TruncateStart, "\x1b[31mlongred\x1b[0m", 4, "\x1b[31m…red\x1b[0m"},
+		{TruncateMiddle, "ab\x1b[1mcdef\x1b[0mgh", 5, "ab\x1b[1m…\x1b[0mgh"},
+		{TruncateMiddle, "abc", 3, "abc"},
+	} {
+		o := &Options{TruncateMode: tt.mode}
+		got := o.truncate(tt.s, tt.w)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) with mode %d: got %q; want %q", tt.s, tt.w, tt.mode, got, tt.want)
+		}
+		if w := o.cellWidth(got); w > tt.w {
+			t.Errorf("truncate(%q, %d) with mode %d has width %d", tt.s, tt.w, tt.mode, w)
+		}
+	}
+}
+
+func TestTruncateKeepsEscapes(t *testing.T) {
+	const s = "\x1b[31mlong\x1b[1mred\x1b[0m"
+	for w := 1; w <= 8; w++ {
+		got := new(Options).truncate(s, w)
+		if stripped := escRegexp.ReplaceAllString(got, ""); strings.ContainsRune(stripped, '\x1b') {
+			t.Errorf("truncate(%q, %d) = %q, which splits an escape sequence", s, w, got)
+		}
+		if !strings.HasSuffix(got, "\x1b[0m") {
+			t.Errorf("truncate(%q, %d) = %q, which lost the reset", s, w, got)
+		}
+	}
+}
+
+func TestWrapLines(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		w    int
+		want []string
+	}{
+		{"", 3, []string{""}},
+		{"abc", 3, []string{"abc"}},
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+		{"世界你好", 2, []string{"世界", "你好"}},
+		{"\x1b[31mabcd\x1b[0m", 2, []string{"\x1b[31mab\x1b[0m", "\x1b[31mcd\x1b[0m"}},
+		{"\x1b[31mab\x1b[0mcd", 2, []string{"\x1b[31mab\x1b[0m", "cd"}},
+		{"\x1b[1m\x1b[4mabc", 2, []string{"\x1b[1m\x1b[4mab\x1b[0m", "\x1b[1m\x1b[4mc"}},
+	} {
+		got := new(Options).wrap(tt.s, tt.w)
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("wrap(%q, %d): (-got, +want):\n%s", tt.s, tt.w, diff)
+		}
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		w    int
+		want []string
+	}{
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"the quick brown fox", 5, []string{"the", "quick", "brown", "fox"}},
+		{"the quick brown fox", 20, []string{"the quick brown fox"}},
+		{"a  b   c", 2, []string{"a", "b", "c"}},
+		{"  indented text", 10, []string{"  indented", "text"}},
+		{"supercalifragilistic", 8, []string{"supercal", "ifragili", "stic"}},
+		{"a supercalifragilistic word", 8, []string{"a", "supercal", "ifragili", "stic", "word"}},
+		{"\x1b[1mbold text\x1b[0m", 4, []string{"\x1b[1mbold\x1b[0m", "\x1b[1mtext\x1b[0m"}},
+	} {
+		o := &Options{WrapMode: WrapWord}
+		got := o.wrap(tt.s, tt.w)
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("wrap(%q, %d): (-got, +want):\n%s", tt.s, tt.w, diff)
+		}
+	}
+}
+
+func TestEastAsianTruncateWrap(t *testing.T) {
+	o := &Options{EastAsianWidth: true}
+	if got, want := o.truncate("世界你好", 5), "世…"; got != want {
+		t.Errorf("truncate: got %q; want %q", got, want)
+	}
+	got := o.wrap("a世界b", 3)
+	want := []string{"a世", "界b"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("wrap: (-got, +want):\n%s", diff)
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"abc", 4, "abc"},
+		{"\t", 4, "    "},
+		{"a\tb", 4, "a   b"},
+		{"abcd\te", 4, "abcd    e"},
+		{"a\t\tb\t", 3, "a     b  "},
+		{"\x1b[1mab\x1b[0m\tc", 4, "\x1b[1mab\x1b[0m  c"},
+	} {
+		if got := new(Options).expandTabs(tt.s, tt.w); got != tt.want {
+			t.Errorf("expandTabs(%q, %d): got %q; want %q", tt.s, tt.w, got, tt.want)
+		}
+	}
+}